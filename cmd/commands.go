@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/cluster"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/generate"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/report"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/scenario"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/snapshot"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// command is a bench subcommand, setup registers command flags and returns command action
+type command struct {
+	name        string
+	args        string
+	description string
+	setup       func(fs *flag.FlagSet) func(ctx context.Context, args []string) error
+}
+
+var commands = []*command{
+	{
+		name:        "run",
+		args:        "<scenario>",
+		description: "run benchmark scenario from YAML or JSON file",
+		setup:       runCmd,
+	},
+	{
+		name:        "all",
+		description: "bench all configs from default scenario",
+		setup:       allCmd,
+	},
+	{
+		name:        "report",
+		args:        "<results-dir>",
+		description: "print cross-config report for results of a run",
+		setup:       reportCmd,
+	},
+	{
+		name:        "compare",
+		args:        "<results-dir> <config-a> <config-b>",
+		description: "run significance tests between two configs results of a run",
+		setup:       compareCmd,
+	},
+	{
+		name:        "snapshot",
+		description: "capture nodes, pods, priority classes, volumes and storage classes from cluster to archive",
+		setup:       snapshotCmd,
+	},
+	{
+		name:        "anonymize",
+		description: "hash names, namespaces and labels, strip env, args, images and annotations of objects from file",
+		setup:       anonymizeCmd,
+	},
+	{
+		name:        "profile-workload",
+		description: "fit workload model of services, requests and constraints from pods file for generate-pods",
+		setup:       profileWorkloadCmd,
+	},
+	{
+		name:        "generate-nodes",
+		args:        "<fleet-spec>",
+		description: "generate synthetic nodes fleet from YAML spec of node templates to file",
+		setup:       generateNodesCmd,
+	},
+	{
+		name:        "generate-pods",
+		args:        "<workload-spec>",
+		description: "generate synthetic workload pods from YAML spec of service groups to file",
+		setup:       generatePodsCmd,
+	},
+	{
+		name:        "import-nodes",
+		description: "import nodes from file to kube-scheduler-simulator",
+		setup:       importNodesCmd,
+	},
+	{
+		name:        "import-pods",
+		description: "import pods from file to kube-scheduler-simulator",
+		setup:       importPodsCmd,
+	},
+	{
+		name:        "import-config",
+		description: "import scheduler config from file to kube-scheduler-simulator",
+		setup:       importConfigCmd,
+	},
+	{
+		name:        "list-nodes",
+		description: "display nodes with scheduled pods and calculate stats - resources imbalance, stranded capacity and headroom",
+		setup:       listNodesCmd,
+	},
+	{
+		name:        "reset",
+		description: "reset kube-scheduler-simulator state",
+		setup:       resetCmd,
+	},
+	{
+		name:        "cut-pods",
+		description: "cut pods file up to limit and write it to file with limit suffix",
+		setup:       cutPodsCmd,
+	},
+}
+
+func runCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+	verbose := addVerboseFlag(fs, false)
+
+	resultsDir := addResultsFlag(fs)
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("exactly one scenario file is required: run <scenario>")
+		}
+
+		return runScenario(ctx, sim, args[0], *resultsDir, *verbose)
+	}
+}
+
+func allCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+	verbose := addVerboseFlag(fs, false)
+	scenarioPath := fs.String("scenario", "./testdata/scenario_all.yaml", "scenario file")
+
+	resultsDir := addResultsFlag(fs)
+
+	return func(ctx context.Context, args []string) error {
+		return runScenario(ctx, sim, *scenarioPath, *resultsDir, *verbose)
+	}
+}
+
+func addResultsFlag(fs *flag.FlagSet) *string {
+	return fs.String("results", "", "results directory, overrides scenario results")
+}
+
+func runScenario(ctx context.Context, sim *simulatorFlags, filePath, resultsDir string, verbose bool) error {
+	s, err := scenario.Load(filePath)
+	if err != nil {
+		return err
+	}
+
+	if resultsDir != "" {
+		s.Results = resultsDir
+	}
+
+	return scenario.Run(ctx, sim.client(), s, verbose)
+}
+
+func reportCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("exactly one results directory is required: report <results-dir>")
+		}
+
+		records, err := result.Load(args[0])
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return fmt.Errorf("no results found in %s", args[0])
+		}
+
+		report.Print(os.Stdout, report.Build(records))
+
+		return nil
+	}
+}
+
+func compareCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	alpha := fs.Float64("alpha", 0.05, "significance level")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 3 {
+			return fmt.Errorf("results directory and two configs are required: compare <results-dir> <config-a> <config-b>")
+		}
+
+		records, err := result.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		configA, a, err := report.FilterConfig(records, args[1])
+		if err != nil {
+			return err
+		}
+		if len(a) == 0 {
+			return fmt.Errorf("no results found for config %s", args[1])
+		}
+
+		configB, b, err := report.FilterConfig(records, args[2])
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return fmt.Errorf("no results found for config %s", args[2])
+		}
+
+		report.PrintComparison(os.Stdout, configA, configB, report.Compare(a, b, *alpha), *alpha)
+
+		return nil
+	}
+}
+
+func snapshotCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	kube := addKubeFlags(fs)
+	out := fs.String("out", "./testdata/snapshot.tar.gz", "snapshot archive file")
+	opts := snapshot.Options{}
+	fs.StringVar(&opts.Nodes.Label, "node-selector", "", "nodes label selector, e.g. node-role.kubernetes.io/worker=")
+	fs.StringVar(&opts.Nodes.Field, "node-field-selector", "", "nodes field selector")
+	fs.StringVar(&opts.Pods.Label, "pod-selector", "", "pods label selector")
+	fs.StringVar(&opts.Pods.Field, "pod-field-selector", "metadata.namespace!=kube-system", "pods field selector, e.g. spec.nodeName!=node1")
+	fs.StringVar(&opts.Namespace, "namespace", "", "pods and persistent volume claims namespace, empty - all namespaces")
+	fs.Int64Var(&opts.PageSize, "page-size", snapshot.DefaultPageSize, "objects per list request")
+
+	return func(ctx context.Context, args []string) error {
+		cs, err := kube.clientset()
+		if err != nil {
+			return err
+		}
+
+		s, err := snapshot.Take(ctx, cs, opts)
+		if err != nil {
+			return err
+		}
+
+		if err = snapshot.WriteFile(*out, s); err != nil {
+			return err
+		}
+
+		log.Printf("Snapshot written to %s: %d nodes, %d pods, %d priority classes, %d pvcs, %d pvs, %d storage classes\n",
+			*out, len(s.Nodes), len(s.Pods), len(s.PriorityClasses), len(s.PersistentVolumeClaims), len(s.PersistentVolumes), len(s.StorageClasses))
+
+		return nil
+	}
+}
+
+func anonymizeCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	filePath := fs.String("file", "./testdata/pods.json", "objects file or directory of manifests")
+	out := fs.String("out", "", "anonymized objects file, defaults to file with .anonymized suffix")
+	kind := fs.String("kind", "Pod", "kind of items without kind field: Pod or Node")
+	anon := addAnonymizeFlags(fs, envString(envAnonymizeKey, ""))
+	format := source.FormatAuto
+	formatVar(fs, &format)
+
+	return func(ctx context.Context, args []string) (err error) {
+		a := anon.anonymizer()
+		if a == nil {
+			return fmt.Errorf("anonymization key is required: --anonymize-key or %s env", envAnonymizeKey)
+		}
+
+		src, err := source.Open(*filePath, format)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		outPath := *out
+		if outPath == "" {
+			outPath = strings.TrimSuffix(*filePath, "/") + ".anonymized"
+		}
+
+		f, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+
+		n, err := a.Stream(src, f, *kind)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Anonymized %d objects to %s\n", n, outPath)
+
+		return nil
+	}
+}
+
+func profileWorkloadCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	filePath := fs.String("file", "./testdata/pods.json", "pods file or directory of manifests")
+	out := fs.String("out", "./testdata/workload_model.yaml", "workload model file")
+	filter := addPodFilterFlags(fs)
+	format := source.FormatAuto
+	formatVar(fs, &format)
+
+	return func(ctx context.Context, args []string) error {
+		src, err := source.Open(*filePath, format)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		m, err := generate.Fit(src, *filter)
+		if err != nil {
+			return err
+		}
+
+		if err = m.WriteFile(*out); err != nil {
+			return err
+		}
+
+		log.Printf("Profiled %d pods of %d services (%d shapes, cpu/memory correlation %.2f) to %s\n",
+			m.Pods, m.Services, len(m.Shapes), m.CPUMemoryCorrelation, *out)
+		log.Printf("Generate similar workload with service group {name: <name>, count: <services>, model: %s}\n", *out)
+
+		return nil
+	}
+}
+
+func generateNodesCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	out := fs.String("out", "./testdata/nodes_generated.json", "generated nodes file")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("exactly one fleet spec file is required: generate-nodes <fleet-spec>")
+		}
+
+		fleet, err := generate.LoadFleet(args[0])
+		if err != nil {
+			return err
+		}
+
+		src, err := fleet.Source()
+		if err != nil {
+			return err
+		}
+
+		n, err := writeList(*out, src)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Generated %d nodes to %s\n", n, *out)
+
+		return nil
+	}
+}
+
+func generatePodsCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	out := fs.String("out", "./testdata/pods_generated.json", "generated pods file")
+	seed := fs.Int64("seed", 0, "seed of workload, overrides spec seed when not 0")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("exactly one workload spec file is required: generate-pods <workload-spec>")
+		}
+
+		workload, err := generate.LoadWorkload(args[0])
+		if err != nil {
+			return err
+		}
+		if *seed != 0 {
+			workload.Seed = *seed
+		}
+
+		src, err := workload.Source()
+		if err != nil {
+			return err
+		}
+
+		n, err := writeList(*out, src)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Generated %d pods to %s\n", n, *out)
+
+		return nil
+	}
+}
+
+// writeList writes items of source to file as a List, returns number of written items
+func writeList(outPath string, src source.Source) (n int, err error) {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	lw, err := source.NewListWriter(f)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		b, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lw.Count(), err
+		}
+		if err = lw.Write(b); err != nil {
+			return lw.Count(), err
+		}
+	}
+
+	return lw.Count(), lw.Close()
+}
+
+func importNodesCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+	verbose := addVerboseFlag(fs, true)
+	filePath := fs.String("file", "./testdata/nodes.json", "nodes file or directory of manifests")
+	limit := fs.Int("limit", 50, "max nodes to import, 0 - no limit")
+	filter := addNodeFilterFlags(fs)
+	opts := addImportFlags(fs)
+	anon := addAnonymizeFlags(fs, "")
+
+	return func(ctx context.Context, args []string) error {
+		nodeImporter, err := _import.NewNodeImporterWithFilter(sim.client(), *limit, *filter)
+		if err != nil {
+			return err
+		}
+		if a := anon.anonymizer(); a != nil {
+			nodeImporter.AddMutators(a.Node)
+		}
+
+		opts.LogEnabled = *verbose
+
+		return _import.ImportNodes(ctx, nodeImporter, *filePath, *opts)
+	}
+}
+
+func importPodsCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+	verbose := addVerboseFlag(fs, true)
+	filePath := fs.String("file", "./testdata/pods.json", "pods file or directory of manifests")
+	limit := fs.Int("limit", 4000, "max pods to import, 0 - no limit")
+	maxPerService := fs.Int("max-per-service", 10, "max pods to import per group (service label value by default, see -group-by), 0 - no cap (it used to import one pod per service)")
+	filter := addPodFilterFlags(fs)
+	opts := addImportFlags(fs)
+	anon := addAnonymizeFlags(fs, "")
+
+	return func(ctx context.Context, args []string) error {
+		filter.MaxPerGroup = *maxPerService
+
+		podImporter, err := _import.NewPodImporterWithFilter(sim.client(), *limit, *filter)
+		if err != nil {
+			return err
+		}
+		if a := anon.anonymizer(); a != nil {
+			podImporter.AddMutators(a.Pod)
+		}
+
+		opts.LogEnabled = *verbose
+
+		return _import.ImportPods(ctx, podImporter, *filePath, *opts)
+	}
+}
+
+func importConfigCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+	filePath := fs.String("file", "./testdata/config_default.json", "scheduler config json file")
+
+	return func(ctx context.Context, args []string) error {
+		return _import.ImportConfig(ctx, sim.client(), *filePath)
+	}
+}
+
+func listNodesCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+	groupBy := fs.String("group-by", _import.DefaultGroupBy, "grouping key of pods into services of headroom stats: label:<key>, owner or namespace")
+
+	return func(ctx context.Context, args []string) error {
+		return cluster.ListNodes(ctx, sim.client(), *groupBy)
+	}
+}
+
+func resetCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	sim := addSimulatorFlags(fs)
+
+	return func(ctx context.Context, args []string) error {
+		return _import.ResetExportState(ctx, sim.client())
+	}
+}
+
+func cutPodsCmd(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	filePath := fs.String("file", "./testdata/pods.json", "pods json file")
+	limit := fs.Int("limit", 5000, "pods to keep")
+
+	return func(ctx context.Context, args []string) error {
+		return _import.CutPods(*filePath, *limit)
+	}
+}
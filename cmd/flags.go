@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/anonymize"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const (
+	envSimulatorHost = "BENCH_SIMULATOR_HOST"
+	envSimulatorPort = "BENCH_SIMULATOR_PORT"
+	envVerbose       = "BENCH_VERBOSE"
+	envAnonymizeKey  = "BENCH_ANONYMIZE_KEY"
+)
+
+// simulatorFlags are kube-scheduler-simulator address flags
+type simulatorFlags struct {
+	host string
+	port int
+}
+
+func addSimulatorFlags(fs *flag.FlagSet) *simulatorFlags {
+	f := &simulatorFlags{}
+
+	fs.StringVar(&f.host, "host", envString(envSimulatorHost, "127.0.0.1"), "kube-scheduler-simulator host (env "+envSimulatorHost+")")
+	fs.IntVar(&f.port, "port", envInt(envSimulatorPort, 1212), "kube-scheduler-simulator port (env "+envSimulatorPort+")")
+
+	return f
+}
+
+func (f *simulatorFlags) client() client.SimulatorClient {
+	return client.New(f.host, f.port)
+}
+
+// kubeFlags are kubernetes cluster access flags, kubeconfig is resolved like kubectl does when not set
+type kubeFlags struct {
+	kubeconfig string
+	context    string
+}
+
+func addKubeFlags(fs *flag.FlagSet) *kubeFlags {
+	f := &kubeFlags{}
+
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "kubeconfig file, defaults to KUBECONFIG env or ~/.kube/config")
+	fs.StringVar(&f.context, "context", "", "kubeconfig context, defaults to current context")
+
+	return f
+}
+
+func (f *kubeFlags) clientset() (kubernetes.Interface, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.kubeconfig
+
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules,
+		&clientcmd.ConfigOverrides{CurrentContext: f.context},
+	).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(cfg)
+}
+
+func addImportFlags(fs *flag.FlagSet) *_import.ImportOptions {
+	opts := &_import.ImportOptions{}
+
+	fs.IntVar(&opts.Workers, "workers", 1, "concurrent import requests, concurrent pods import makes scheduling order non-reproducible")
+	fs.Float64Var(&opts.RateLimit, "rate-limit", 0, "max import requests per second, 0 - no limit")
+	fs.IntVar(&opts.Burst, "burst", 1, "import requests allowed above rate limit at once")
+	formatVar(fs, &opts.Format)
+
+	return opts
+}
+
+// formatVar registers input format flag, format is detected by file extension and content by default
+func formatVar(fs *flag.FlagSet, p *source.Format) {
+	*p = source.FormatAuto
+	fs.Func("format", "input format: json, ndjson or yaml, detected by file extension and content by default", func(v string) (err error) {
+		*p, err = source.ParseFormat(v)
+		return err
+	})
+}
+
+// anonymizeFlags are anonymization hash key and label keys kept as is
+type anonymizeFlags struct {
+	key           string
+	keepLabelKeys string
+}
+
+func addAnonymizeFlags(fs *flag.FlagSet, defKey string) *anonymizeFlags {
+	f := &anonymizeFlags{}
+
+	fs.StringVar(&f.key, "anonymize-key", defKey, "secret key of names and labels hashes, same key gives same hashes")
+	fs.StringVar(&f.keepLabelKeys, "keep-label-keys", "service", "comma separated label keys to keep as is, values are hashed anyway")
+
+	return f
+}
+
+// anonymizer returns nil if key is not set
+func (f *anonymizeFlags) anonymizer() *anonymize.Anonymizer {
+	if f.key == "" {
+		return nil
+	}
+
+	return anonymize.New([]byte(f.key), splitList(f.keepLabelKeys))
+}
+
+func addPodFilterFlags(fs *flag.FlagSet) *_import.PodFilter {
+	f := &_import.PodFilter{}
+
+	listVar(fs, &f.Namespaces, "namespaces", "import only pods of comma separated namespaces")
+	listVar(fs, &f.ExcludeNamespaces, "exclude-namespaces", "skip pods of comma separated namespaces")
+	fs.StringVar(&f.Selector, "selector", "", "pods label selector, e.g. 'app in (web, db),tier!=cache'")
+	listVar(fs, &f.ExcludeOwnerKinds, "exclude-owner-kinds", "skip pods owned by comma separated kinds, e.g. DaemonSet,Job")
+	listVar(fs, &f.Phases, "phases", "import only pods in comma separated phases, e.g. Running,Pending")
+	listVar(fs, &f.PriorityClasses, "priority-classes", "import only pods with comma separated priority class names")
+	fs.StringVar(&f.CPU.Min, "cpu-min", "", "min pod cpu requests, e.g. 100m, effective requests with init containers, sidecars and overhead are compared")
+	fs.StringVar(&f.CPU.Max, "cpu-max", "", "max pod cpu requests")
+	fs.StringVar(&f.Memory.Min, "memory-min", "", "min pod memory requests, e.g. 256Mi")
+	fs.StringVar(&f.Memory.Max, "memory-max", "", "max pod memory requests")
+	fs.StringVar(&f.GroupBy, "group-by", _import.DefaultGroupBy, "grouping key of per group cap: label:<key>, owner or namespace")
+
+	return f
+}
+
+func addNodeFilterFlags(fs *flag.FlagSet) *_import.NodeFilter {
+	f := &_import.NodeFilter{}
+
+	fs.StringVar(&f.Selector, "selector", "", "nodes label selector, e.g. 'node-role.kubernetes.io/worker,pool!=gpu'")
+	listVar(fs, &f.Taints, "taints", "import only nodes with all comma separated taints: key[=value][:Effect]")
+	listVar(fs, &f.ExcludeTaints, "exclude-taints", "skip nodes with any of comma separated taints: key[=value][:Effect]")
+	fs.StringVar(&f.CPU.Min, "cpu-min", "", "min node allocatable cpu, e.g. 4")
+	fs.StringVar(&f.CPU.Max, "cpu-max", "", "max node allocatable cpu")
+	fs.StringVar(&f.Memory.Min, "memory-min", "", "min node allocatable memory, e.g. 16Gi")
+	fs.StringVar(&f.Memory.Max, "memory-max", "", "max node allocatable memory")
+	listVar(fs, &f.InstanceTypes, "instance-types", "import only nodes of comma separated instance types")
+	listVar(fs, &f.Zones, "zones", "import only nodes of comma separated zones")
+	fs.IntVar(&f.CoresEq, "cores-eq", 0, "import only nodes with given allocatable cpu, 0 - any")
+	fs.StringVar(&f.Stratify, "stratify", "", "keep proportion of every stratum when limited: shape, instanceType, zone or label:<key>")
+
+	return f
+}
+
+// listVar registers comma separated list flag
+func listVar(fs *flag.FlagSet, p *[]string, name, usage string) {
+	fs.Func(name, usage, func(v string) error {
+		*p = splitList(v)
+		return nil
+	})
+}
+
+func splitList(v string) []string {
+	out := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
+
+func addVerboseFlag(fs *flag.FlagSet, def bool) *bool {
+	return fs.Bool("v", envBool(envVerbose, def), "verbose logging (env "+envVerbose+")")
+}
+
+func envString(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+
+	return def
+}
+
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func envBool(key string, def bool) bool {
+	if v, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return v
+	}
+
+	return def
+}
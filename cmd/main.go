@@ -1,101 +1,76 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
-	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/cluster"
-	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
 	"log"
 	"os"
-	"time"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
-var availableCommands = "Available commands: \n\t- import-nodes\n\t- import-pods\n\t- list-nodes\n\t- reset"
-
 func main() {
 	args := os.Args[1:]
 
-	if len(args) > 0 {
-		runCommand(args[0])
-	} else {
-		log.Println(availableCommands)
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(os.Stderr, usage())
+		return
 	}
-}
 
-func runCommand(cmd string) {
-	var err error
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		log.Fatalf("error: command %s not implemented\n%s", args[0], usage())
+	}
 
-	c := client.New("127.0.0.1", 1212)
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bench %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.description)
+		fs.PrintDefaults()
+	}
 
-	switch cmd {
-	case "all":
-		configs := []string{
-			"./testdata/config_default.json",
-			"./testdata/config_all_leastalloc_cpu5.json",
-			"./testdata/config_all_leastalloc_cpu100.json",
-			"./testdata/config_leastalloc_cpu5.json",
-			"./testdata/config_leastalloc_cpu100.json",
-		}
+	run := cmd.setup(fs)
 
-		nodesFilePath := "./testdata/nodes.json"
-		podsFilePath := "./testdata/pods.json"
-		nodesLimit := 5
-		podsLimit := 300
-		iterationsPerConfig := 5
+	err := fs.Parse(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
 
-		log.Printf("Run modelling for nodes: %d, pods: %d, iterations per config: %d...\n", nodesLimit, podsLimit, iterationsPerConfig)
+	// SIGINT/SIGTERM cancel context, so commands stop gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		for i := range configs {
-			for j := 0; j < iterationsPerConfig; j++ {
-				log.Printf("Config: %s, iteration: %d...\n", configs[i], j+1)
-				if err = _import.ResetExportState(c); err != nil {
-					log.Fatal("error:", err)
-				}
-				//log.Printf("Importing config %s...\n", configs[i])
-				if err = _import.ImportConfig(c, configs[i]); err != nil {
-					log.Fatal("error:", err)
-				}
+	if err = run(ctx, fs.Args()); err != nil {
+		stop()
+		log.Fatal("error: ", err)
+	}
 
-				//log.Println("Config imported - OK")
-				//log.Println("Importing nodes...")
-				if err = _import.ImportNodes(_import.NewNodeImporter(c, nodesLimit, 88), nodesFilePath, false); err != nil {
-					log.Fatal("error:", err)
-				}
-				//log.Println("Nodes imported - OK")
-				//log.Println("Importing pods...")
-				if err = _import.ImportPods(_import.NewPodImporter(c, podsLimit, 3), podsFilePath, false); err != nil {
-					log.Fatal("error:", err)
-				}
-				//log.Println("Pods imported - OK")
-				time.Sleep(3 * time.Second)
+	log.Printf("Command `%s` finished successfully", cmd.name)
+}
 
-				if err = cluster.ListNodes(c); err != nil {
-					log.Fatal("error:", err)
-				}
-			}
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
 		}
-	case "import-nodes":
-		nodeImporter := _import.NewNodeImporter(c, 50, 88)
-		err = _import.ImportNodes(nodeImporter, "./testdata/nodes.json", true)
-	case "cut-pods":
-		err = _import.CutPods("./testdata/pods.json", 5000)
-	case "import-pods":
-		podImporter := _import.NewPodImporter(c, 4000, 10)
-		err = _import.ImportPods(podImporter, "./testdata/pods.json", true)
-	case "import-config":
-		err = _import.ImportConfig(c, "./testdata/config_default.json")
-	case "list-nodes":
-		err = cluster.ListNodes(c)
-	case "reset":
-		err = _import.ResetExportState(c)
-	default:
-		err = fmt.Errorf("command %s not implemented. %s", cmd, availableCommands)
 	}
 
-	if err != nil {
-		log.Fatal("error:", err)
-		return
+	return nil
+}
+
+func usage() string {
+	var b strings.Builder
+
+	b.WriteString("Usage: bench <command> [flags] [args]\n\nAvailable commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "  %-14s %s\n", cmd.name, cmd.description)
 	}
+	b.WriteString("\nRun `bench <command> --help` for command flags.\n")
 
-	log.Printf("Command `%s` finished successfully", cmd)
+	return b.String()
 }
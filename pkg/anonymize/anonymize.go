@@ -0,0 +1,394 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// Image replaces images of all containers
+const Image = "anonymized"
+
+// hashLen is hex length of hashes, 60 bits are enough to avoid collisions in a cluster dump
+const hashLen = 15
+
+// keepValueKeys are well-known labels with values that affect scheduling and identify nothing
+var keepValueKeys = map[string]bool{
+	"kubernetes.io/os":                         true,
+	"kubernetes.io/arch":                       true,
+	"beta.kubernetes.io/os":                    true,
+	"beta.kubernetes.io/arch":                  true,
+	"node.kubernetes.io/instance-type":         true,
+	"beta.kubernetes.io/instance-type":         true,
+	"topology.kubernetes.io/zone":              true,
+	"topology.kubernetes.io/region":            true,
+	"failure-domain.beta.kubernetes.io/zone":   true,
+	"failure-domain.beta.kubernetes.io/region": true,
+}
+
+// Anonymizer replaces names, namespaces, labels and node names of kubernetes objects with keyed hashes
+// and strips env, args, images and annotations, resources are kept as is.
+// Equal values get equal hashes, so label selectors, affinity and topology spread constraints keep matching.
+// Integer label values are kept, so Gt and Lt node affinity requirements keep comparing them.
+type Anonymizer struct {
+	key      []byte
+	keepKeys map[string]bool
+}
+
+// New returns anonymizer with secret hash key, keepLabelKeys are label keys kept as is (their values are hashed),
+// keys of well-known kubernetes.io and k8s.io labels are always kept
+func New(key []byte, keepLabelKeys []string) *Anonymizer {
+	a := &Anonymizer{
+		key:      key,
+		keepKeys: map[string]bool{},
+	}
+	for _, k := range keepLabelKeys {
+		a.keepKeys[k] = true
+	}
+
+	return a
+}
+
+// Hash returns keyed hash of s, valid as object name, label key and label value, empty string is kept empty
+func (a *Anonymizer) Hash(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(s))
+
+	return "a" + hex.EncodeToString(mac.Sum(nil))[:hashLen]
+}
+
+// Stream anonymizes items of src and writes them to w as a List, kind is used for items without kind field.
+// Returns number of written items
+func (a *Anonymizer) Stream(src source.Source, w io.Writer, kind string) (int, error) {
+	lw, err := source.NewListWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		b, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lw.Count(), err
+		}
+
+		root, err := insaneJSON.DecodeBytes(b)
+		if err != nil {
+			return lw.Count(), fmt.Errorf("item %d: %w", lw.Count(), err)
+		}
+
+		if root.Dig("kind").AsString() == "" {
+			root.AddField("kind").MutateToString(kind)
+		}
+		a.Object(root.Node)
+
+		err = lw.Write(root.EncodeToByte())
+		insaneJSON.Release(root)
+		if err != nil {
+			return lw.Count(), err
+		}
+	}
+
+	return lw.Count(), lw.Close()
+}
+
+// Object anonymizes object according to its kind, objects of unknown kinds get only metadata anonymized
+func (a *Anonymizer) Object(obj *insaneJSON.Node) {
+	switch obj.Dig("kind").AsString() {
+	case "Pod":
+		a.Pod(obj)
+	case "Node":
+		a.Node(obj)
+	case "PersistentVolumeClaim":
+		a.metadata(obj)
+		a.hashString(obj.Dig("spec", "volumeName"))
+		a.selector(obj.Dig("spec", "selector"))
+	case "PersistentVolume":
+		a.metadata(obj)
+		a.hashString(obj.Dig("spec", "claimRef", "name"))
+		a.hashString(obj.Dig("spec", "claimRef", "namespace"))
+		obj.Dig("spec", "claimRef", "uid").Suicide()
+		for _, term := range obj.Dig("spec", "nodeAffinity", "required", "nodeSelectorTerms").AsArray() {
+			a.nodeSelectorTerm(term)
+		}
+	default:
+		a.metadata(obj)
+	}
+}
+
+// Pod anonymizes pod, it has Mutator signature to be used as import hook
+func (a *Anonymizer) Pod(pod *insaneJSON.Node) {
+	a.metadata(pod)
+
+	spec := pod.Dig("spec")
+	for _, f := range []string{"nodeName", "hostname", "subdomain", "serviceAccountName", "serviceAccount"} {
+		a.hashString(spec.Dig(f))
+	}
+	for _, f := range []string{"imagePullSecrets", "dnsConfig", "hostAliases"} {
+		spec.Dig(f).Suicide()
+	}
+
+	a.labels(spec.Dig("nodeSelector"))
+
+	for _, f := range []string{"containers", "initContainers", "ephemeralContainers"} {
+		for _, c := range spec.Dig(f).AsArray() {
+			a.container(c)
+		}
+	}
+
+	for _, v := range spec.Dig("volumes").AsArray() {
+		a.volume(v)
+	}
+
+	a.affinity(spec.Dig("affinity"))
+
+	for _, c := range spec.Dig("topologySpreadConstraints").AsArray() {
+		a.labelKey(c.Dig("topologyKey"))
+		a.selector(c.Dig("labelSelector"))
+	}
+
+	for _, t := range spec.Dig("tolerations").AsArray() {
+		a.labelValue(t.Dig("key").AsString(), t.Dig("value"))
+		a.labelKey(t.Dig("key"))
+	}
+
+	// only phase and qos class are kept, the rest is ips, images and messages
+	keepFields(pod.Dig("status"), "phase", "qosClass")
+}
+
+// Node anonymizes node, it has Mutator signature to be used as import hook
+func (a *Anonymizer) Node(node *insaneJSON.Node) {
+	a.metadata(node)
+
+	spec := node.Dig("spec")
+	for _, f := range []string{"podCIDR", "podCIDRs", "providerID", "configSource"} {
+		spec.Dig(f).Suicide()
+	}
+
+	for _, t := range spec.Dig("taints").AsArray() {
+		a.labelValue(t.Dig("key").AsString(), t.Dig("value"))
+		a.labelKey(t.Dig("key"))
+	}
+
+	status := node.Dig("status")
+	for _, f := range []string{"addresses", "images", "volumesAttached", "volumesInUse", "config"} {
+		status.Dig(f).Suicide()
+	}
+	for _, f := range []string{"machineID", "systemUUID", "bootID"} {
+		status.Dig("nodeInfo", f).Suicide()
+	}
+}
+
+func (a *Anonymizer) metadata(obj *insaneJSON.Node) {
+	meta := obj.Dig("metadata")
+	for _, f := range []string{"name", "generateName", "namespace", "uid"} {
+		a.hashString(meta.Dig(f))
+	}
+	for _, f := range []string{"annotations", "managedFields", "resourceVersion", "selfLink"} {
+		meta.Dig(f).Suicide()
+	}
+
+	a.labels(meta.Dig("labels"))
+
+	for _, ref := range meta.Dig("ownerReferences").AsArray() {
+		a.hashString(ref.Dig("name"))
+		a.hashString(ref.Dig("uid"))
+	}
+}
+
+func (a *Anonymizer) container(c *insaneJSON.Node) {
+	a.hashString(c.Dig("name"))
+
+	if image := c.Dig("image"); image != nil {
+		image.MutateToString(Image)
+	}
+
+	for _, f := range []string{"env", "envFrom", "command", "args", "workingDir", "livenessProbe", "readinessProbe", "startupProbe", "lifecycle"} {
+		c.Dig(f).Suicide()
+	}
+
+	for _, p := range c.Dig("ports").AsArray() {
+		a.hashString(p.Dig("name"))
+	}
+
+	for _, m := range c.Dig("volumeMounts").AsArray() {
+		a.hashString(m.Dig("name"))
+		a.hashString(m.Dig("subPath"))
+		if p := m.Dig("mountPath"); p != nil {
+			p.MutateToString("/" + a.Hash(p.AsString()))
+		}
+	}
+}
+
+// volume keeps persistent volume claims as they affect scheduling, other volumes are replaced with emptyDir
+func (a *Anonymizer) volume(v *insaneJSON.Node) {
+	a.hashString(v.Dig("name"))
+
+	if pvc := v.Dig("persistentVolumeClaim"); pvc != nil {
+		a.hashString(pvc.Dig("claimName"))
+		return
+	}
+
+	keepFields(v, "name")
+	v.AddField("emptyDir").MutateToObject()
+}
+
+func (a *Anonymizer) affinity(affinity *insaneJSON.Node) {
+	nodeAffinity := affinity.Dig("nodeAffinity")
+	for _, term := range nodeAffinity.Dig("requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms").AsArray() {
+		a.nodeSelectorTerm(term)
+	}
+	for _, p := range nodeAffinity.Dig("preferredDuringSchedulingIgnoredDuringExecution").AsArray() {
+		a.nodeSelectorTerm(p.Dig("preference"))
+	}
+
+	for _, f := range []string{"podAffinity", "podAntiAffinity"} {
+		for _, term := range affinity.Dig(f, "requiredDuringSchedulingIgnoredDuringExecution").AsArray() {
+			a.podAffinityTerm(term)
+		}
+		for _, p := range affinity.Dig(f, "preferredDuringSchedulingIgnoredDuringExecution").AsArray() {
+			a.podAffinityTerm(p.Dig("podAffinityTerm"))
+		}
+	}
+}
+
+func (a *Anonymizer) podAffinityTerm(term *insaneJSON.Node) {
+	a.selector(term.Dig("labelSelector"))
+	a.selector(term.Dig("namespaceSelector"))
+	a.labelKey(term.Dig("topologyKey"))
+
+	for _, ns := range term.Dig("namespaces").AsArray() {
+		a.hashString(ns)
+	}
+}
+
+func (a *Anonymizer) nodeSelectorTerm(term *insaneJSON.Node) {
+	for _, expr := range term.Dig("matchExpressions").AsArray() {
+		a.requirement(expr)
+	}
+
+	// the only supported field is metadata.name
+	for _, expr := range term.Dig("matchFields").AsArray() {
+		for _, v := range expr.Dig("values").AsArray() {
+			a.hashString(v)
+		}
+	}
+}
+
+// selector anonymizes metav1.LabelSelector
+func (a *Anonymizer) selector(sel *insaneJSON.Node) {
+	a.labels(sel.Dig("matchLabels"))
+
+	for _, expr := range sel.Dig("matchExpressions").AsArray() {
+		a.requirement(expr)
+	}
+}
+
+// requirement anonymizes label selector requirement, values of Gt and Lt operators are integers and kept
+// like integer label values they are compared with
+func (a *Anonymizer) requirement(expr *insaneJSON.Node) {
+	key := expr.Dig("key").AsString()
+
+	for _, v := range expr.Dig("values").AsArray() {
+		a.labelValue(key, v)
+	}
+
+	a.labelKey(expr.Dig("key"))
+}
+
+func (a *Anonymizer) labels(labels *insaneJSON.Node) {
+	for _, field := range labels.AsFields() {
+		key := field.AsString()
+
+		a.labelValue(key, field.AsFieldValue())
+		field.MutateToField(a.hashLabelKey(key))
+	}
+}
+
+// labelKey hashes label key stored in string node
+func (a *Anonymizer) labelKey(n *insaneJSON.Node) {
+	if n.IsString() {
+		n.MutateToString(a.hashLabelKey(n.AsString()))
+	}
+}
+
+// labelValue hashes value of label with given key stored in string node, values of well-known keys
+// and integers are kept
+func (a *Anonymizer) labelValue(key string, n *insaneJSON.Node) {
+	if keepValueKeys[key] || isInteger(n.AsString()) {
+		return
+	}
+
+	a.hashString(n)
+}
+
+func (a *Anonymizer) hashLabelKey(key string) string {
+	if a.keepKeys[key] || isWellKnownKey(key) {
+		return key
+	}
+
+	return a.Hash(key)
+}
+
+func (a *Anonymizer) hashString(n *insaneJSON.Node) {
+	if n.IsString() {
+		n.MutateToString(a.Hash(n.AsString()))
+	}
+}
+
+// isWellKnownKey reports if label key is in kubernetes.io or k8s.io domain
+func isWellKnownKey(key string) bool {
+	i := strings.IndexByte(key, '/')
+	if i < 0 {
+		return false
+	}
+
+	domain := key[:i]
+	for _, d := range []string{"kubernetes.io", "k8s.io"} {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInteger reports if label value is an integer as Gt and Lt selector operators parse it
+func isInteger(v string) bool {
+	_, err := strconv.ParseInt(v, 10, 64)
+	return err == nil
+}
+
+// keepFields removes all object fields except given ones
+func keepFields(obj *insaneJSON.Node, keep ...string) {
+	remove := []string{}
+	for _, field := range obj.AsFields() {
+		name := field.AsString()
+
+		kept := false
+		for _, k := range keep {
+			kept = kept || name == k
+		}
+		if !kept {
+			remove = append(remove, name)
+		}
+	}
+
+	for _, name := range remove {
+		obj.Dig(name).Suicide()
+	}
+}
@@ -0,0 +1,286 @@
+package anonymize
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const testNode = `{
+	"kind": "Node",
+	"metadata": {
+		"name": "worker-1.corp.internal",
+		"labels": {
+			"kubernetes.io/hostname": "worker-1.corp.internal",
+			"topology.kubernetes.io/zone": "eu-west-1a",
+			"corp.internal/pool": "payments"
+		},
+		"annotations": {"corp.internal/owner": "team-payments"}
+	},
+	"spec": {
+		"providerID": "aws:///eu-west-1a/i-0abc",
+		"taints": [{"key": "corp.internal/dedicated", "value": "payments", "effect": "NoSchedule"}]
+	},
+	"status": {
+		"allocatable": {"cpu": "8", "memory": "32Gi"},
+		"addresses": [{"type": "InternalIP", "address": "10.0.0.1"}],
+		"nodeInfo": {"machineID": "abc", "kubeletVersion": "v1.23.4"}
+	}
+}`
+
+const testPod = `{
+	"kind": "Pod",
+	"metadata": {
+		"name": "payments-api-7d9f",
+		"namespace": "payments",
+		"labels": {"service": "payments-api", "corp.internal/team": "payments"},
+		"annotations": {"secret": "value"}
+	},
+	"spec": {
+		"nodeName": "worker-1.corp.internal",
+		"nodeSelector": {"corp.internal/pool": "payments"},
+		"tolerations": [{"key": "corp.internal/dedicated", "operator": "Equal", "value": "payments", "effect": "NoSchedule"}],
+		"affinity": {
+			"nodeAffinity": {
+				"requiredDuringSchedulingIgnoredDuringExecution": {
+					"nodeSelectorTerms": [{"matchExpressions": [
+						{"key": "topology.kubernetes.io/zone", "operator": "In", "values": ["eu-west-1a"]},
+						{"key": "corp.internal/cores", "operator": "Gt", "values": ["4"]}
+					]}]
+				}
+			},
+			"podAntiAffinity": {
+				"requiredDuringSchedulingIgnoredDuringExecution": [{
+					"labelSelector": {"matchLabels": {"service": "payments-api"}},
+					"namespaces": ["payments"],
+					"topologyKey": "kubernetes.io/hostname"
+				}]
+			}
+		},
+		"topologySpreadConstraints": [{
+			"maxSkew": 1,
+			"topologyKey": "corp.internal/pool",
+			"labelSelector": {"matchExpressions": [{"key": "corp.internal/team", "operator": "In", "values": ["payments"]}]}
+		}],
+		"containers": [{
+			"name": "api",
+			"image": "registry.corp.internal/payments/api:1.2.3",
+			"args": ["--db-password=secret"],
+			"env": [{"name": "DB_HOST", "value": "db.corp.internal"}],
+			"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}},
+			"volumeMounts": [{"name": "certs", "mountPath": "/etc/certs"}]
+		}],
+		"volumes": [
+			{"name": "certs", "secret": {"secretName": "payments-certs"}},
+			{"name": "data", "persistentVolumeClaim": {"claimName": "payments-data"}}
+		]
+	},
+	"status": {"phase": "Running", "podIP": "10.1.0.1", "qosClass": "Burstable"}
+}`
+
+func anonymize(t *testing.T, a *Anonymizer, items ...string) []map[string]interface{} {
+	t.Helper()
+
+	in := bufio.NewReader(strings.NewReader(strings.Join(compact(t, items), "\n")))
+	src, err := source.New(in, source.FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	n, err := a.Stream(src, out, "Pod")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if n != len(items) {
+		t.Fatalf("Stream() wrote %d items, want %d", n, len(items))
+	}
+
+	list := struct {
+		Items []map[string]interface{} `json:"items"`
+	}{}
+	if err = json.Unmarshal(out.Bytes(), &list); err != nil {
+		t.Fatalf("Stream() wrote invalid json: %v: %s", err, out)
+	}
+
+	return list.Items
+}
+
+func compact(t *testing.T, items []string) []string {
+	out := []string{}
+	for _, item := range items {
+		buf := &bytes.Buffer{}
+		if err := json.Compact(buf, []byte(item)); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, buf.String())
+	}
+
+	return out
+}
+
+func dig(v interface{}, path ...interface{}) interface{} {
+	for _, p := range path {
+		switch p := p.(type) {
+		case string:
+			m, _ := v.(map[string]interface{})
+			v = m[p]
+		case int:
+			a, _ := v.([]interface{})
+			if p >= len(a) {
+				return nil
+			}
+			v = a[p]
+		}
+	}
+
+	return v
+}
+
+func TestAnonymizePreservesEquality(t *testing.T) {
+	a := New([]byte("secret"), []string{"service"})
+	items := anonymize(t, a, testNode, testPod)
+	node, pod := items[0], items[1]
+
+	nodeName := dig(node, "metadata", "name")
+	if nodeName != a.Hash("worker-1.corp.internal") {
+		t.Errorf("node name = %v, want hash", nodeName)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"pod node name", dig(pod, "spec", "nodeName"), nodeName},
+		{"hostname label", dig(node, "metadata", "labels", "kubernetes.io/hostname"), nodeName},
+		{"zone label is kept", dig(node, "metadata", "labels", "topology.kubernetes.io/zone"), "eu-west-1a"},
+		{"node selector", dig(pod, "spec", "nodeSelector", a.Hash("corp.internal/pool")), dig(node, "metadata", "labels", a.Hash("corp.internal/pool"))},
+		{"toleration key", dig(pod, "spec", "tolerations", 0, "key"), dig(node, "spec", "taints", 0, "key")},
+		{"toleration value", dig(pod, "spec", "tolerations", 0, "value"), dig(node, "spec", "taints", 0, "value")},
+		{"kept label key", dig(pod, "metadata", "labels", "service"), a.Hash("payments-api")},
+		{"anti affinity selector", dig(pod, "spec", "affinity", "podAntiAffinity", "requiredDuringSchedulingIgnoredDuringExecution", 0, "labelSelector", "matchLabels", "service"), a.Hash("payments-api")},
+		{"anti affinity namespace", dig(pod, "spec", "affinity", "podAntiAffinity", "requiredDuringSchedulingIgnoredDuringExecution", 0, "namespaces", 0), dig(pod, "metadata", "namespace")},
+		{"anti affinity topology key", dig(pod, "spec", "affinity", "podAntiAffinity", "requiredDuringSchedulingIgnoredDuringExecution", 0, "topologyKey"), "kubernetes.io/hostname"},
+		{"node affinity zone", dig(pod, "spec", "affinity", "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms", 0, "matchExpressions", 0, "values", 0), "eu-west-1a"},
+		{"node affinity gt value", dig(pod, "spec", "affinity", "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms", 0, "matchExpressions", 1, "values", 0), "4"},
+		{"spread topology key", dig(pod, "spec", "topologySpreadConstraints", 0, "topologyKey"), a.Hash("corp.internal/pool")},
+		{"spread selector key", dig(pod, "spec", "topologySpreadConstraints", 0, "labelSelector", "matchExpressions", 0, "key"), a.Hash("corp.internal/team")},
+		{"spread selector value", dig(pod, "spec", "topologySpreadConstraints", 0, "labelSelector", "matchExpressions", 0, "values", 0), dig(pod, "metadata", "labels", a.Hash("corp.internal/team"))},
+		{"mount name", dig(pod, "spec", "containers", 0, "volumeMounts", 0, "name"), dig(pod, "spec", "volumes", 0, "name")},
+		{"claim name", dig(pod, "spec", "volumes", 1, "persistentVolumeClaim", "claimName"), a.Hash("payments-data")},
+	}
+
+	for _, tt := range tests {
+		if tt.got == nil || tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestAnonymizeStripsSensitiveData(t *testing.T) {
+	items := anonymize(t, New([]byte("secret"), nil), testNode, testPod)
+	node, pod := items[0], items[1]
+
+	b, _ := json.Marshal(items)
+	for _, leak := range []string{"corp.internal", "payments", "secret", "10.0.0.1", "10.1.0.1", "i-0abc", "/etc/certs"} {
+		if bytes.Contains(b, []byte(leak)) {
+			t.Errorf("anonymized items contain %q: %s", leak, b)
+		}
+	}
+
+	container := dig(pod, "spec", "containers", 0)
+	for _, f := range []string{"env", "args"} {
+		if dig(container, f) != nil {
+			t.Errorf("container %s is not removed", f)
+		}
+	}
+	if got := dig(container, "image"); got != Image {
+		t.Errorf("container image = %v, want %s", got, Image)
+	}
+	if got := dig(container, "resources", "requests", "cpu"); got != "500m" {
+		t.Errorf("container cpu request = %v, want 500m", got)
+	}
+	if dig(pod, "metadata", "annotations") != nil || dig(node, "metadata", "annotations") != nil {
+		t.Error("annotations are not removed")
+	}
+	if dig(pod, "spec", "volumes", 0, "emptyDir") == nil || dig(pod, "spec", "volumes", 0, "secret") != nil {
+		t.Errorf("secret volume is not replaced with emptyDir: %v", dig(pod, "spec", "volumes", 0))
+	}
+	if got := dig(pod, "status"); len(got.(map[string]interface{})) != 2 {
+		t.Errorf("pod status = %v, want phase and qosClass only", got)
+	}
+	if got := dig(node, "status", "allocatable", "memory"); got != "32Gi" {
+		t.Errorf("node allocatable memory = %v, want 32Gi", got)
+	}
+	if got := dig(node, "status", "nodeInfo", "kubeletVersion"); got != "v1.23.4" {
+		t.Errorf("node kubelet version = %v, want v1.23.4", got)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, b := New([]byte("a"), nil), New([]byte("b"), nil)
+
+	if a.Hash("x") != a.Hash("x") {
+		t.Error("Hash() is not consistent")
+	}
+	if a.Hash("x") == b.Hash("x") {
+		t.Error("Hash() does not depend on key")
+	}
+	if a.Hash("") != "" {
+		t.Error("Hash() of empty string is not empty")
+	}
+}
+
+func TestAnonymizeKeepsNumericRequirements(t *testing.T) {
+	a := New([]byte("secret"), nil)
+	items := anonymize(t, a, `{
+		"kind": "Node",
+		"metadata": {"name": "worker-1", "labels": {"corp.internal/cpu-count": "96", "corp.internal/pool": "batch"}}
+	}`, `{
+		"kind": "Pod",
+		"metadata": {"name": "job-1"},
+		"spec": {"affinity": {"nodeAffinity": {"requiredDuringSchedulingIgnoredDuringExecution": {"nodeSelectorTerms": [{"matchExpressions": [
+			{"key": "corp.internal/cpu-count", "operator": "Gt", "values": ["64"]},
+			{"key": "corp.internal/cpu-count", "operator": "Lt", "values": ["128"]},
+			{"key": "corp.internal/pool", "operator": "In", "values": ["batch"]}
+		]}]}}}}
+	}`)
+	node, pod := items[0], items[1]
+
+	nodeLabels := labels.Set{}
+	for k, v := range dig(node, "metadata", "labels").(map[string]interface{}) {
+		nodeLabels[k] = v.(string)
+	}
+
+	if nodeLabels[a.Hash("corp.internal/pool")] != a.Hash("batch") {
+		t.Errorf("node labels = %v, want hashed pool value", nodeLabels)
+	}
+
+	exprs := dig(pod, "spec", "affinity", "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms", 0, "matchExpressions").([]interface{})
+	for _, e := range exprs {
+		expr := e.(map[string]interface{})
+
+		values := []string{}
+		for _, v := range expr["values"].([]interface{}) {
+			values = append(values, v.(string))
+		}
+
+		op := map[string]selection.Operator{"Gt": selection.GreaterThan, "Lt": selection.LessThan, "In": selection.In}[expr["operator"].(string)]
+		req, err := labels.NewRequirement(expr["key"].(string), op, values)
+		if err != nil {
+			t.Fatalf("anonymized requirement %v is invalid: %v", expr, err)
+		}
+
+		if !req.Matches(nodeLabels) {
+			t.Errorf("anonymized requirement %v does not match anonymized node labels %v", expr, nodeLabels)
+		}
+	}
+}
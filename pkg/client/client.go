@@ -2,123 +2,186 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"time"
 )
 
+// Timeouts are per-operation deadlines, retries are included
+type Timeouts struct {
+	Apply  time.Duration
+	Config time.Duration
+	Reset  time.Duration
+	List   time.Duration
+}
+
+// DefaultTimeouts are used by clients created with New
+var DefaultTimeouts = Timeouts{
+	Apply:  5 * time.Minute,
+	Config: time.Minute,
+	Reset:  5 * time.Minute,
+	List:   time.Minute,
+}
+
 // HTTPClient is a scheduler simulator http client
 type HTTPClient struct {
 	c              *http.Client
 	baseURLPattern string
+	timeouts       Timeouts
+	retryPolicies  map[Operation]RetryPolicy
 }
 
 // New returns new client
 func New(host string, port int) *HTTPClient {
 	return &HTTPClient{
-		c: &http.Client{
-			Timeout: 5 * time.Minute,
-		},
+		c:              &http.Client{},
 		baseURLPattern: fmt.Sprintf("http://%s:%d/api/v1/", host, port) + "%s",
+		timeouts:       DefaultTimeouts,
+		retryPolicies:  DefaultRetryPolicies,
+	}
+}
+
+// WithTimeouts returns client copy with given per-operation deadlines
+func (c *HTTPClient) WithTimeouts(timeouts Timeouts) *HTTPClient {
+	cp := *c
+	cp.timeouts = timeouts
+
+	return &cp
+}
+
+// WithRetryPolicy returns client copy using given retry policy for operation
+func (c *HTTPClient) WithRetryPolicy(op Operation, policy RetryPolicy) *HTTPClient {
+	cp := *c
+	cp.retryPolicies = make(map[Operation]RetryPolicy, len(c.retryPolicies)+1)
+	for k, v := range c.retryPolicies {
+		cp.retryPolicies[k] = v
+	}
+	cp.retryPolicies[op] = policy
+
+	return &cp
+}
+
+// retryPolicy returns operation retry policy, unknown operations are not retried
+func (c *HTTPClient) retryPolicy(op Operation) RetryPolicy {
+	if p, ok := c.retryPolicies[op]; ok {
+		return p
 	}
+
+	return NoRetry
 }
 
 // ApplyNodes push nodes to scheduler simulator
-func (c *HTTPClient) ApplyNodes(nodesJSON []byte) (*http.Response, error) {
-	req, err := http.NewRequest("POST", c.methodURL("nodes"), bytes.NewReader(nodesJSON))
+func (c *HTTPClient) ApplyNodes(ctx context.Context, nodesJSON []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.Apply)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.methodURL("nodes"), bytes.NewReader(nodesJSON))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	return c.c.Do(req)
+	return cancelOnClose(cancel)(c.DoWithRetry(req, c.retryPolicy(OpApplyNodes)))
 }
 
 // ApplyPods push pods to scheduler simulator
-func (c *HTTPClient) ApplyPods(podJSON []byte) (*http.Response, error) {
-	req, err := http.NewRequest("POST", c.methodURL("pods"), bytes.NewReader(podJSON))
+func (c *HTTPClient) ApplyPods(ctx context.Context, podJSON []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.Apply)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.methodURL("pods"), bytes.NewReader(podJSON))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	return c.DoWithRetry(req, 30, 3*time.Second)
+	return cancelOnClose(cancel)(c.DoWithRetry(req, c.retryPolicy(OpApplyPods)))
 }
 
 // ApplyConfig push config to scheduler simulator
-func (c *HTTPClient) ApplyConfig(cfg []byte) (*http.Response, error) {
-	req, err := http.NewRequest("POST", c.methodURL("schedulerconfiguration"), bytes.NewReader(cfg))
+func (c *HTTPClient) ApplyConfig(ctx context.Context, cfg []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.Config)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.methodURL("schedulerconfiguration"), bytes.NewReader(cfg))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	return c.c.Do(req)
+	return cancelOnClose(cancel)(c.DoWithRetry(req, c.retryPolicy(OpApplyConfig)))
 }
 
 // Reset fully resets scheduler simulator state
-func (c *HTTPClient) Reset() (*http.Response, error) {
-	req, err := http.NewRequest("PUT", c.methodURL("reset"), nil)
+func (c *HTTPClient) Reset(ctx context.Context) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.Reset)
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.methodURL("reset"), nil)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return c.DoWithRetry(req, 10, 2*time.Second)
+	return cancelOnClose(cancel)(c.DoWithRetry(req, c.retryPolicy(OpReset)))
 }
 
 // ListPods returns all pods in cluster
-func (c *HTTPClient) ListPods() (*http.Response, error) {
-	req, err := http.NewRequest("GET", c.methodURL("pods"), nil)
+func (c *HTTPClient) ListPods(ctx context.Context) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.List)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.methodURL("pods"), nil)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return c.c.Do(req)
+	return cancelOnClose(cancel)(c.DoWithRetry(req, c.retryPolicy(OpListPods)))
 }
 
 // ListNodes returns all nodes in cluster
-func (c *HTTPClient) ListNodes() (*http.Response, error) {
-	req, err := http.NewRequest("GET", c.methodURL("nodes"), nil)
+func (c *HTTPClient) ListNodes(ctx context.Context) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.List)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.methodURL("nodes"), nil)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return c.c.Do(req)
+	return cancelOnClose(cancel)(c.DoWithRetry(req, c.retryPolicy(OpListNodes)))
 }
 
 func (c *HTTPClient) methodURL(method string) string {
 	return fmt.Sprintf(c.baseURLPattern, method)
 }
 
-// DoWithRetry retries to send request N attempts
-func (c *HTTPClient) DoWithRetry(req *http.Request, attempts int, sleep time.Duration) (*http.Response, error) {
-	var err error
-	var resp *http.Response
+// cancelBody releases operation context when response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
 
-	for i := 0; i < attempts; i++ {
-		if i > 0 {
-			status := 0
-			if resp != nil {
-				status = resp.StatusCode
-			}
+func (b *cancelBody) Close() error {
+	defer b.cancel()
 
-			log.Printf("retrying in 2s after error: %s, code: %d", err, status)
-			time.Sleep(sleep)
-			sleep *= 2
-		}
-		resp, err = c.c.Do(req)
+	return b.ReadCloser.Close()
+}
 
+// cancelOnClose binds operation context cancel to response body, so the body can be read after method returns
+func cancelOnClose(cancel context.CancelFunc) func(resp *http.Response, err error) (*http.Response, error) {
+	return func(resp *http.Response, err error) (*http.Response, error) {
 		if err != nil {
-			continue
+			cancel()
+			return nil, err
 		}
 
-		if resp.StatusCode < http.StatusInternalServerError && err == nil {
-			return resp, nil
-		}
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+		return resp, nil
 	}
-	return nil, fmt.Errorf("after %d attempts, last error: %s", attempts, err)
 }
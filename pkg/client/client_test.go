@@ -0,0 +1,215 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client/fake"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		fault     fake.Fault
+		attempts  int
+		retryable func(resp *http.Response, err error) bool
+		wantErr   bool
+		wantReqs  int
+	}{
+		{
+			name:     "server errors",
+			fault:    fake.Fault{Times: 2, Status: http.StatusServiceUnavailable},
+			attempts: 3,
+			wantReqs: 3,
+		},
+		{
+			name:     "dropped connections",
+			fault:    fake.Fault{Times: 2, Drop: true},
+			attempts: 3,
+			wantReqs: 3,
+		},
+		{
+			name:     "attempts exceeded",
+			fault:    fake.Fault{Times: 3, Status: http.StatusInternalServerError},
+			attempts: 3,
+			wantErr:  true,
+			wantReqs: 3,
+		},
+		{
+			name:     "too many requests",
+			fault:    fake.Fault{Times: 1, Status: http.StatusTooManyRequests},
+			attempts: 3,
+			wantReqs: 2,
+		},
+		{
+			name:     "conflict is not retried",
+			fault:    fake.Fault{Times: 1, Status: http.StatusConflict},
+			attempts: 3,
+			wantReqs: 1,
+		},
+		{
+			name:      "conflict of idempotent operation",
+			fault:     fake.Fault{Times: 1, Status: http.StatusConflict},
+			attempts:  3,
+			retryable: client.IsRetryableConflict,
+			wantReqs:  2,
+		},
+		{
+			name:     "client errors are not retried",
+			fault:    fake.Fault{Times: 1, Status: http.StatusBadRequest},
+			attempts: 3,
+			wantReqs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fake.NewServer()
+			defer s.Close()
+
+			s.InjectFault(tt.fault)
+
+			req, err := http.NewRequest(http.MethodGet, s.URL+"/api/v1/nodes", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := s.Client().DoWithRetry(req, client.RetryPolicy{MaxAttempts: tt.attempts, BaseDelay: time.Millisecond, Retryable: tt.retryable})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DoWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+
+			if got := s.Requests(http.MethodGet, "/api/v1/nodes"); got != tt.wantReqs {
+				t.Errorf("DoWithRetry() sent %d requests, want %d", got, tt.wantReqs)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	c := s.Client()
+
+	resp, err := c.ApplyNodes(context.Background(), []byte(`{"metadata": {"name": "node1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	resp, err = c.Reset(context.Background())
+	if err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted || len(s.Nodes()) != 0 {
+		t.Errorf("Reset() status = %d, nodes left = %d", resp.StatusCode, len(s.Nodes()))
+	}
+}
+
+func TestDroppedConnectionThenConflict(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	for _, path := range []string{"/api/v1/pods", "/api/v1/reset"} {
+		s.InjectFault(fake.Fault{Path: path, Times: 1, Drop: true})
+		s.InjectFault(fake.Fault{Path: path, Times: 1, Status: http.StatusConflict})
+	}
+
+	policy := client.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}
+	resetPolicy := policy
+	resetPolicy.Retryable = client.IsRetryableConflict
+
+	c := s.Client().WithRetryPolicy(client.OpApplyPods, policy).WithRetryPolicy(client.OpReset, resetPolicy)
+
+	// pod may be already created by the dropped attempt, so conflict is returned to the caller instead of retrying
+	resp, err := c.ApplyPods(context.Background(), []byte(`{"metadata": {"name": "pod1"}}`))
+	if err != nil {
+		t.Fatalf("ApplyPods() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict || s.Requests(http.MethodPost, "/api/v1/pods") != 2 {
+		t.Errorf("ApplyPods() status = %d after %d requests, want conflict after 2", resp.StatusCode, s.Requests(http.MethodPost, "/api/v1/pods"))
+	}
+
+	resp, err = c.Reset(context.Background())
+	if err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted || s.Requests(http.MethodPut, "/api/v1/reset") != 3 {
+		t.Errorf("Reset() status = %d after %d requests, want accepted after 3", resp.StatusCode, s.Requests(http.MethodPut, "/api/v1/reset"))
+	}
+}
+
+func TestDoWithRetryCancel(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	s.InjectFault(fake.Fault{Times: 10, Status: http.StatusServiceUnavailable})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/api/v1/nodes", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+
+	_, err = s.Client().DoWithRetry(req, client.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DoWithRetry() error = %v, want deadline exceeded", err)
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Errorf("DoWithRetry() did not stop on context cancellation")
+	}
+}
+
+func TestDoWithRetryReplaysBody(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	s.InjectFault(fake.Fault{Method: http.MethodPost, Path: "/api/v1/pods", Times: 2, Status: http.StatusBadGateway})
+
+	c := s.Client().WithRetryPolicy(client.OpApplyPods, client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	resp, err := c.ApplyPods(context.Background(), []byte(`{"metadata": {"name": "pod1"}}`))
+	if err != nil {
+		t.Fatalf("ApplyPods() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || len(s.Pods()) != 1 {
+		t.Errorf("ApplyPods() status = %d, pods = %v", resp.StatusCode, s.Pods())
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := client.RetryPolicy{BaseDelay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second}
+
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := p.Delay(retry); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", retry, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Delay(2); got < time.Second || got > 2*time.Second {
+			t.Fatalf("Delay(2) with jitter = %s, want in [1s, 2s]", got)
+		}
+	}
+}
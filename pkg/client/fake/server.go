@@ -0,0 +1,340 @@
+// Package fake provides in-process kube-scheduler-simulator for hermetic tests
+package fake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+)
+
+// Object is a kubernetes object decoded from JSON
+type Object = map[string]interface{}
+
+// PlacementFunc chooses node for a pod, empty node name marks pod unschedulable
+type PlacementFunc func(pod Object, nodes []Object) string
+
+// Fault is an injected failure for requests matching Method and Path
+type Fault struct {
+	// Method and Path match request, empty value matches any
+	Method string
+	Path   string
+	// Times is a number of requests to fail
+	Times int
+	// Latency delays the response
+	Latency time.Duration
+	// Status responds with given status code
+	Status int
+	// Drop closes connection without response
+	Drop bool
+}
+
+// Server is a fake kube-scheduler-simulator with in-memory state
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	nodes     map[string]Object
+	pods      map[string]Object
+	config    Object
+	placement PlacementFunc
+	faults    []*Fault
+	requests  map[string]int
+}
+
+// NewServer starts fake simulator with round-robin placement
+func NewServer() *Server {
+	s := &Server{
+		nodes:     map[string]Object{},
+		pods:      map[string]Object{},
+		placement: RoundRobin(),
+		requests:  map[string]int{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/nodes", s.handleNodes)
+	mux.HandleFunc("/api/v1/pods", s.handlePods)
+	mux.HandleFunc("/api/v1/schedulerconfiguration", s.handleConfig)
+	mux.HandleFunc("/api/v1/reset", s.handleReset)
+
+	s.Server = httptest.NewServer(s.withFaults(mux))
+
+	return s
+}
+
+// Client returns simulator client pointed to the fake server
+func (s *Server) Client() *client.HTTPClient {
+	u, _ := url.Parse(s.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	return client.New(u.Hostname(), port)
+}
+
+// SetPlacement replaces pods placement function
+func (s *Server) SetPlacement(placement PlacementFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.placement = placement
+}
+
+// InjectFault adds failure for matching requests, faults are applied in order of injection
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// Requests returns number of requests received by method and path, e.g. "POST /api/v1/pods"
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[method+" "+path]
+}
+
+// Nodes returns stored nodes sorted by name
+func (s *Server) Nodes() []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedObjects(s.nodes)
+}
+
+// Pods returns stored pods sorted by name
+func (s *Server) Pods() []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedObjects(s.pods)
+}
+
+// Config returns applied scheduler config
+func (s *Server) Config() Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.config
+}
+
+func (s *Server) withFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.Method+" "+r.URL.Path]++
+		f := s.takeFault(r)
+		s.mu.Unlock()
+
+		if f != nil {
+			time.Sleep(f.Latency)
+
+			if f.Drop {
+				if hj, ok := w.(http.Hijacker); ok {
+					if conn, _, err := hj.Hijack(); err == nil {
+						_ = conn.Close()
+						return
+					}
+				}
+			}
+
+			if f.Status != 0 {
+				http.Error(w, http.StatusText(f.Status), f.Status)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeFault returns first matching fault and decrements its counter, must be called under lock
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != r.Method) || (f.Path != "" && f.Path != r.URL.Path) {
+			continue
+		}
+
+		f.Times--
+		if f.Times <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+
+		return f
+	}
+
+	return nil
+}
+
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		writeJSON(w, Object{"kind": "NodeList", "items": sortedObjects(s.nodes)})
+	case http.MethodPost:
+		node, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.nodes[objectName(node)] = node
+		writeJSON(w, node)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handlePods(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		writeJSON(w, Object{"kind": "PodList", "items": sortedObjects(s.pods)})
+	case http.MethodPost:
+		pod, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.schedule(pod)
+		s.pods[objectName(pod)] = pod
+		writeJSON(w, pod)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		writeJSON(w, s.config)
+	case http.MethodPost:
+		cfg, ok := readObject(w, r)
+		if !ok {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.config = cfg
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nodes = map[string]Object{}
+	s.pods = map[string]Object{}
+	s.config = nil
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// schedule binds pod to a node chosen by placement or marks it unschedulable, must be called under lock
+func (s *Server) schedule(pod Object) {
+	spec, _ := pod["spec"].(Object)
+	if spec == nil {
+		spec = Object{}
+		pod["spec"] = spec
+	}
+
+	nodeName := s.placement(pod, sortedObjects(s.nodes))
+	if nodeName != "" {
+		spec["nodeName"] = nodeName
+		return
+	}
+
+	delete(spec, "nodeName")
+	pod["status"] = Object{
+		"phase": "Pending",
+		"conditions": []interface{}{
+			Object{"type": "PodScheduled", "status": "False", "reason": "Unschedulable"},
+		},
+	}
+}
+
+// RoundRobin places pods on nodes in turn ignoring resources
+func RoundRobin() PlacementFunc {
+	next := 0
+
+	return func(pod Object, nodes []Object) string {
+		if len(nodes) == 0 {
+			return ""
+		}
+
+		node := nodes[next%len(nodes)]
+		next++
+
+		return objectName(node)
+	}
+}
+
+func readObject(w http.ResponseWriter, r *http.Request) (Object, bool) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	obj := Object{}
+	if err = json.Unmarshal(b, &obj); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return obj, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func objectName(obj Object) string {
+	metadata, _ := obj["metadata"].(Object)
+	name, _ := metadata["name"].(string)
+
+	return name
+}
+
+func sortedObjects(objects map[string]Object) []Object {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]Object, 0, len(names))
+	for _, name := range names {
+		out = append(out, objects[name])
+	}
+
+	return out
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Operation is a simulator client operation which has its own retry policy
+type Operation string
+
+const (
+	OpApplyNodes  Operation = "apply-nodes"
+	OpApplyPods   Operation = "apply-pods"
+	OpApplyConfig Operation = "apply-config"
+	OpReset       Operation = "reset"
+	OpListPods    Operation = "list-pods"
+	OpListNodes   Operation = "list-nodes"
+)
+
+// RetryPolicy describes how failed requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is a max number of attempts including the first one
+	MaxAttempts int
+	// BaseDelay is a delay before the first retry, next delays grow exponentially with Multiplier
+	BaseDelay  time.Duration
+	Multiplier float64
+	// MaxDelay caps delay between attempts, 0 - no cap
+	MaxDelay time.Duration
+	// Jitter is a fraction of delay randomized to spread retries, in [0, 1]
+	Jitter float64
+	// Retryable classifies attempt result, IsRetryable is used when nil
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// NoRetry sends request once
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// DefaultRetryPolicies are used by clients created with New
+var DefaultRetryPolicies = map[Operation]RetryPolicy{
+	OpApplyNodes:  {MaxAttempts: 5, BaseDelay: time.Second, Multiplier: 2, MaxDelay: 10 * time.Second, Jitter: 0.2},
+	OpApplyPods:   {MaxAttempts: 30, BaseDelay: 3 * time.Second, Multiplier: 2, MaxDelay: 30 * time.Second, Jitter: 0.2},
+	OpApplyConfig: {MaxAttempts: 5, BaseDelay: time.Second, Multiplier: 2, MaxDelay: 10 * time.Second, Jitter: 0.2, Retryable: IsRetryableConflict},
+	OpReset:       {MaxAttempts: 10, BaseDelay: 2 * time.Second, Multiplier: 2, MaxDelay: 30 * time.Second, Jitter: 0.2, Retryable: IsRetryableConflict},
+	OpListPods:    {MaxAttempts: 3, BaseDelay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second, Jitter: 0.2},
+	OpListNodes:   {MaxAttempts: 3, BaseDelay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second, Jitter: 0.2},
+}
+
+// IsRetryable retries connection errors, server errors and 429 Too Many Requests
+func IsRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusTooManyRequests
+}
+
+// IsRetryableConflict retries 409 Conflict as well as IsRetryable does. It is meant for idempotent operations
+// like reset and config where conflict is a transient state of simulator. Conflict of creating request
+// may mean that the object was created by a previous attempt which response was lost, so it is not retried.
+func IsRetryableConflict(resp *http.Response, err error) bool {
+	if err == nil && resp.StatusCode == http.StatusConflict {
+		return true
+	}
+
+	return IsRetryable(resp, err)
+}
+
+// Delay returns delay before given retry, retry numbering starts from 1
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// DoWithRetry sends request retrying it according to policy, request body is replayed on every attempt.
+// Waiting between attempts is interrupted by request context.
+func (c *HTTPClient) DoWithRetry(req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			delay := policy.Delay(i)
+			log.Printf("retrying %s %s in %s after %s (attempt %d/%d)", req.Method, req.URL.Path, delay, attemptError(resp, err), i+1, attempts)
+
+			discardBody(resp)
+
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(delay):
+			}
+
+			if req, err = rewindBody(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = c.c.Do(req)
+
+		if !retryable(resp, err) {
+			return resp, err
+		}
+	}
+
+	if err == nil {
+		discardBody(resp)
+	}
+
+	return nil, fmt.Errorf("after %d attempts, last error: %s", attempts, attemptError(resp, err))
+}
+
+// rewindBody returns request copy with a fresh body for the next attempt
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body of %s %s can't be replayed", req.Method, req.URL.Path)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	next := req.Clone(req.Context())
+	next.Body = body
+
+	return next, nil
+}
+
+func attemptError(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("response status %d", resp.StatusCode)
+}
+
+func discardBody(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
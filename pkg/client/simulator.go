@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// SimulatorClient is a kube-scheduler-simulator API client used by importers and analytics
+type SimulatorClient interface {
+	// ApplyNodes push node to scheduler simulator
+	ApplyNodes(ctx context.Context, nodesJSON []byte) (*http.Response, error)
+	// ApplyPods push pod to scheduler simulator
+	ApplyPods(ctx context.Context, podJSON []byte) (*http.Response, error)
+	// ApplyConfig push scheduler config to scheduler simulator
+	ApplyConfig(ctx context.Context, cfg []byte) (*http.Response, error)
+	// Reset fully resets scheduler simulator state
+	Reset(ctx context.Context) (*http.Response, error)
+	// ListPods returns all pods in cluster
+	ListPods(ctx context.Context) (*http.Response, error)
+	// ListNodes returns all nodes in cluster
+	ListNodes(ctx context.Context) (*http.Response, error)
+}
+
+var _ SimulatorClient = (*HTTPClient)(nil)
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client/fake"
+)
+
+func TestAnalyze(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	s.SetPlacement(func(pod fake.Object, nodes []fake.Object) string {
+		switch pod["metadata"].(fake.Object)["name"] {
+		case "pod1", "pod2":
+			return "node1"
+		case "pod3":
+			return "node2"
+		}
+		return ""
+	})
+
+	c := s.Client()
+
+	for _, node := range []string{
+		`{"metadata": {"name": "node1"}, "status": {"allocatable": {"cpu": "8", "memory": "16G", "pods": "110", "nvidia.com/gpu": "4"}}}`,
+		`{"metadata": {"name": "node2"}, "status": {"allocatable": {"cpu": "8", "memory": "16G", "pods": "110", "ephemeral-storage": "100Gi"}}}`,
+	} {
+		resp, err := c.ApplyNodes(context.Background(), []byte(node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	for _, pod := range []string{"pod1", "pod2", "pod3", "pod4"} {
+		resp, err := c.ApplyPods(context.Background(), []byte(`{"metadata": {"name": "`+pod+`"}, "spec": {"containers": [
+			{"resources": {"requests": {"cpu": "1500m", "memory": "2G", "nvidia.com/gpu": "1"}}}
+		]}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	a, err := Analyze(context.Background(), c, "")
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	if a.PodsCount != 4 || !reflect.DeepEqual(a.UnscheduledPods, []string{"pod4"}) {
+		t.Errorf("Analyze() pods = %d, unscheduled = %v", a.PodsCount, a.UnscheduledPods)
+	}
+
+	if n := a.Nodes["node1"]; n.AllocatedPods != 2 || n.Allocated["cpu"] != 3000 || n.AllocatedCores != 3 || n.Allocated["memory"] != 4000000000 || n.Allocatable["memory"] != 16000000000 || n.Allocated["pods"] != 2 {
+		t.Errorf("Analyze() node1 = %+v", a.Nodes["node1"])
+	}
+	if n := a.Nodes["node2"]; n.Allocatable["cpu"] != 8000 || n.Allocated["cpu"] != 1500 || n.Allocatable["ephemeral-storage"] != 100<<30 {
+		t.Errorf("Analyze() node2 = %+v", n)
+	}
+
+	wantCPU := Stats{Min: 1.5, Max: 3, Imbalance: 1.5, StdDev: 0.75}
+	if a.CPU != wantCPU {
+		t.Errorf("Analyze() CPU = %+v, want %+v", a.CPU, wantCPU)
+	}
+
+	if want := []string{"cpu", "memory", "pods", "ephemeral-storage", "nvidia.com/gpu"}; !reflect.DeepEqual(a.ResourceNames, want) {
+		t.Errorf("Analyze() resource names = %v, want %v", a.ResourceNames, want)
+	}
+	if s := a.Resources["nvidia.com/gpu"]; s != (Stats{Min: 1, Max: 2, Imbalance: 1, StdDev: 0.5}) {
+		t.Errorf("Analyze() gpu stats = %+v", s)
+	}
+	if u := a.Utilization["cpu"]; u.Min != 0.1875 || u.Max != 0.375 || u.Imbalance != 0.1875 {
+		t.Errorf("Analyze() cpu utilization = %+v", u)
+	}
+	if _, ok := a.Utilization["ephemeral-storage"]; !ok {
+		t.Errorf("Analyze() no ephemeral-storage utilization, want stats of node2")
+	}
+
+	if s := a.Resources["memory"]; s != a.Mem || s.Max != 4000000000.0/(1<<30) {
+		t.Errorf("Analyze() memory stats = %+v, Mem = %+v", s, a.Mem)
+	}
+
+	if a.Fragmentation.GroupBy != "label:service" {
+		t.Errorf("Analyze() fragmentation groupBy = %s, want default label:service", a.Fragmentation.GroupBy)
+	}
+
+	if _, err = Analyze(context.Background(), c, "label"); err == nil {
+		t.Error("Analyze() error = nil, want invalid groupBy error")
+	}
+}
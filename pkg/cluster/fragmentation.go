@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"math"
+	"sort"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+// Fragmentation is a free capacity of nodes which pods can not use after scheduling.
+// Free cpu of a node is stranded if its free memory can't fit the smallest memory request of pods and vice versa,
+// all free capacity of a node without free pod slots is stranded. Fits account cpu, memory, extended resources and pods slots only, selectors, affinity and taints are ignored
+type Fragmentation struct {
+	// FreeCPU and FreeMemGb are free capacity of all nodes
+	FreeCPU   float64 `json:"freeCpu"`
+	FreeMemGb float64 `json:"freeMemGb"`
+	// StrandedCPU is free cpu of nodes without memory for the smallest pod, StrandedCPUNodes is number of such nodes
+	StrandedCPU      float64 `json:"strandedCpu"`
+	StrandedCPUNodes int     `json:"strandedCpuNodes"`
+	// StrandedMemGb is free memory of nodes without cpu for the smallest pod, StrandedMemNodes is number of such nodes
+	StrandedMemGb    float64 `json:"strandedMemGb"`
+	StrandedMemNodes int     `json:"strandedMemNodes"`
+	// Smallest are the smallest non-zero cpu and memory requests of pods, thresholds of stranded capacity
+	Smallest Resources `json:"smallest"`
+	// LargestCPUFit and LargestMemFit are the largest pod shapes that still fit any node: free capacity of the node
+	// with the most free cpu and of the node with the most free memory, empty if no node has a free pod slot
+	LargestCPUFit Resources `json:"largestCpuFit"`
+	LargestMemFit Resources `json:"largestMemFit"`
+	// GroupBy is a grouping key of pods into services: label:<key>, owner or namespace, like import pods filter groupBy
+	GroupBy string `json:"groupBy"`
+	// Services are extra replicas of every service pod shape the cluster could absorb, sorted by service name
+	Services []ServiceHeadroom `json:"services"`
+}
+
+// ServiceHeadroom is a number of extra replicas of service pod shape fitting free capacity of nodes
+type ServiceHeadroom struct {
+	Service string `json:"service"`
+	Pods    int    `json:"pods"`
+	// Shape is the largest effective requests of service pods per resource
+	Shape Resources `json:"shape"`
+	// Headroom is a number of extra replicas, -1 if shape requests nothing limited on nodes
+	Headroom int `json:"headroom"`
+}
+
+// podShapes returns pod shapes of services grouped by groupKey and the smallest non-zero cpu and memory requests of all pods,
+// pods with empty group key are not grouped
+func podShapes(pods *insaneJSON.Node, groupKey func(pod *insaneJSON.Node) string) ([]ServiceHeadroom, Resources) {
+	services := map[string]*ServiceHeadroom{}
+	smallest := Resources{}
+
+	for _, pod := range pods.AsArray() {
+		effective, _ := podRequests(pod)
+
+		for _, name := range []string{resourceCPU, resourceMemory} {
+			if v := effective[name]; v > 0 && (smallest[name] == 0 || v < smallest[name]) {
+				smallest[name] = v
+			}
+		}
+
+		service := groupKey(pod)
+		if service == "" {
+			continue
+		}
+
+		s, ok := services[service]
+		if !ok {
+			s = &ServiceHeadroom{Service: copyString(service), Shape: Resources{}}
+			services[s.Service] = s
+		}
+		s.Pods++
+		s.Shape.max(effective)
+	}
+
+	out := make([]ServiceHeadroom, 0, len(services))
+	for _, s := range services {
+		out = append(out, *s)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Service < out[j].Service
+	})
+
+	return out, smallest
+}
+
+// analyzeFragmentation calculates stranded capacity, the largest fitting shapes and headroom of every service shape
+func analyzeFragmentation(nodes map[string]*Node, groupBy string, services []ServiceHeadroom, smallest Resources) Fragmentation {
+	f := Fragmentation{Smallest: smallest, GroupBy: groupBy, Services: services}
+
+	var maxCPU, maxMem *Node
+
+	for _, node := range sortedNodes(nodes) {
+		freeCPU, freeMem := node.free(resourceCPU), node.free(resourceMemory)
+
+		f.FreeCPU += ResourceValue(resourceCPU, max64(freeCPU, 0))
+		f.FreeMemGb += ResourceValue(resourceMemory, max64(freeMem, 0))
+
+		_, limited := node.Allocatable[resourcePods]
+		noSlots := limited && node.free(resourcePods) < 1
+
+		if freeCPU > 0 && (noSlots || freeMem < max64(smallest[resourceMemory], 1)) {
+			f.StrandedCPU += ResourceValue(resourceCPU, freeCPU)
+			f.StrandedCPUNodes++
+		}
+		if freeMem > 0 && (noSlots || freeCPU < max64(smallest[resourceCPU], 1)) {
+			f.StrandedMemGb += ResourceValue(resourceMemory, freeMem)
+			f.StrandedMemNodes++
+		}
+
+		if noSlots {
+			continue
+		}
+		if maxCPU == nil || freeCPU > maxCPU.free(resourceCPU) {
+			maxCPU = node
+		}
+		if maxMem == nil || freeMem > maxMem.free(resourceMemory) {
+			maxMem = node
+		}
+	}
+
+	if maxCPU != nil {
+		f.LargestCPUFit = maxCPU.freeShape()
+		f.LargestMemFit = maxMem.freeShape()
+	}
+
+	for i := range f.Services {
+		f.Services[i].Headroom = headroom(nodes, f.Services[i].Shape)
+	}
+
+	return f
+}
+
+// headroom returns number of pods of shape fitting free capacity of nodes, -1 if shape is not limited by any node
+func headroom(nodes map[string]*Node, shape Resources) int {
+	total, limited := 0, false
+
+	for _, node := range nodes {
+		fit := int64(math.MaxInt64)
+
+		for name, v := range shape {
+			if v > 0 {
+				fit = min64(fit, max64(node.free(name), 0)/v)
+			}
+		}
+		if _, ok := node.Allocatable[resourcePods]; ok {
+			fit = min64(fit, max64(node.free(resourcePods), 0))
+		}
+
+		if fit == math.MaxInt64 {
+			continue
+		}
+
+		limited = true
+		total += int(fit)
+	}
+
+	if !limited {
+		return -1
+	}
+
+	return total
+}
+
+// free returns allocatable minus allocated amount of resource, negative if node is overcommitted
+func (n *Node) free(name string) int64 {
+	return n.Allocatable[name] - n.Allocated[name]
+}
+
+// freeShape returns free cpu and memory of node
+func (n *Node) freeShape() Resources {
+	return Resources{
+		resourceCPU:    max64(n.free(resourceCPU), 0),
+		resourceMemory: max64(n.free(resourceMemory), 0),
+	}
+}
+
+func sortedNodes(nodes map[string]*Node) []*Node {
+	out := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		out = append(out, node)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
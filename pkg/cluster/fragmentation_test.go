@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+)
+
+func Test_podShapes(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"items": [
+		{"metadata": {"namespace": "shop", "labels": {"service": "web"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}},
+		{"metadata": {"namespace": "shop", "labels": {"service": "web"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "1", "memory": "512Mi"}}}]}},
+		{"metadata": {"namespace": "shop", "labels": {"service": "db"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "2", "memory": "8Gi"}}}]}},
+		{"metadata": {"namespace": "jobs"}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m"}}}]}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insaneJSON.Release(root)
+
+	tests := []struct {
+		groupBy string
+		want    []ServiceHeadroom
+	}{
+		{
+			"label:service",
+			[]ServiceHeadroom{
+				{Service: "db", Pods: 1, Shape: Resources{"cpu": 2000, "memory": 8 << 30}},
+				{Service: "web", Pods: 2, Shape: Resources{"cpu": 1000, "memory": 1 << 30}},
+			},
+		},
+		{
+			"namespace",
+			[]ServiceHeadroom{
+				{Service: "jobs", Pods: 1, Shape: Resources{"cpu": 100}},
+				{Service: "shop", Pods: 3, Shape: Resources{"cpu": 2000, "memory": 8 << 30}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			groupKey, err := _import.ParseGroupBy(tt.groupBy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			services, smallest := podShapes(root.Dig("items"), groupKey)
+			if !reflect.DeepEqual(services, tt.want) {
+				t.Errorf("podShapes() services = %+v, want %+v", services, tt.want)
+			}
+			if !smallest.equal(Resources{"cpu": 100, "memory": 512 << 20}) {
+				t.Errorf("podShapes() smallest = %+v", smallest)
+			}
+		})
+	}
+}
+
+func Test_analyzeFragmentation(t *testing.T) {
+	nodes := map[string]*Node{
+		// free cpu without memory
+		"cpu-left": {Name: "cpu-left", Allocatable: Resources{"cpu": 8000, "memory": 16 << 30, "pods": 110}, Allocated: Resources{"cpu": 2000, "memory": 16<<30 - 100<<20, "pods": 10}},
+		// free memory without cpu
+		"mem-left": {Name: "mem-left", Allocatable: Resources{"cpu": 8000, "memory": 16 << 30, "pods": 110}, Allocated: Resources{"cpu": 8000, "memory": 4 << 30, "pods": 10}},
+		"balanced": {Name: "balanced", Allocatable: Resources{"cpu": 8000, "memory": 16 << 30, "pods": 110}, Allocated: Resources{"cpu": 6000, "memory": 12 << 30, "pods": 10}},
+		// no pod slots left
+		"full": {Name: "full", Allocatable: Resources{"cpu": 64000, "memory": 256 << 30, "pods": 10}, Allocated: Resources{"pods": 10}},
+	}
+	services := []ServiceHeadroom{
+		{Service: "web", Pods: 2, Shape: Resources{"cpu": 1000, "memory": 1 << 30}},
+		{Service: "gpu", Pods: 1, Shape: Resources{"cpu": 1000, "nvidia.com/gpu": 1}},
+		{Service: "tiny", Pods: 1, Shape: Resources{}},
+	}
+
+	f := analyzeFragmentation(nodes, "label:service", services, Resources{"cpu": 250, "memory": 256 << 20})
+
+	if f.StrandedCPU != 70 || f.StrandedCPUNodes != 2 || f.StrandedMemGb != 268 || f.StrandedMemNodes != 2 {
+		t.Errorf("analyzeFragmentation() stranded = %+v", f)
+	}
+	if f.FreeCPU != 72 || f.FreeMemGb != 16+256+100.0/1024 {
+		t.Errorf("analyzeFragmentation() free cpu = %v, mem = %v", f.FreeCPU, f.FreeMemGb)
+	}
+
+	if !f.LargestCPUFit.equal(Resources{"cpu": 6000, "memory": 100 << 20}) || !f.LargestMemFit.equal(Resources{"memory": 12 << 30}) {
+		t.Errorf("analyzeFragmentation() largest fit = %+v, %+v", f.LargestCPUFit, f.LargestMemFit)
+	}
+
+	// web fits 2 pods on balanced only, gpu fits nowhere, tiny is limited by pod slots
+	for i, want := range []int{2, 0, 300} {
+		if f.Services[i].Headroom != want {
+			t.Errorf("analyzeFragmentation() %s headroom = %d, want %d", f.Services[i].Service, f.Services[i].Headroom, want)
+		}
+	}
+}
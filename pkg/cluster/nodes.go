@@ -1,84 +1,215 @@
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math"
 	"net/http"
 	"os"
-	"strconv"
 
 	"github.com/olekukonko/tablewriter"
 	insaneJSON "github.com/vitkovskii/insane-json"
-	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
 	"gonum.org/v1/gonum/stat"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
 )
 
+// Node is a cluster node with allocated resources of scheduled pods.
+// Resources are accounted by name in exact units (see Resources), every scheduled pod allocates one of pods resource,
+// cores and GiB of cpu and memory are derived from them
 type Node struct {
-	Name             string
-	AllocatableCores float64
-	AllocatableMemGb float64
-	AllocatedCores   float64
-	AllocatedMemGb   float64
-	AllocatedPods    int
-	Pods             []string
+	Name             string    `json:"name"`
+	Allocatable      Resources `json:"allocatable"`
+	Allocated        Resources `json:"allocated"`
+	AllocatableCores float64   `json:"allocatableCores"`
+	AllocatableMemGb float64   `json:"allocatableMemGb"`
+	AllocatedCores   float64   `json:"allocatedCores"`
+	AllocatedMemGb   float64   `json:"allocatedMemGb"`
+	AllocatedPods    int       `json:"allocatedPods"`
+	Pods             []string  `json:"pods"`
+}
+
+// Stats is a resource allocation spread across nodes in reported units, see ResourceValue
+type Stats struct {
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Imbalance float64 `json:"imbalance"`
+	StdDev    float64 `json:"stddev"`
+}
+
+// Analysis is a snapshot of pods placement on cluster nodes
+type Analysis struct {
+	Nodes           map[string]*Node
+	PodsCount       int
+	UnscheduledPods []string
+	// RequestDiffs are scheduled pods which init containers, sidecars or overhead make effective requests
+	// differ from sum of containers requests
+	RequestDiffs []PodRequestDiff
+	// ResourceNames are resources allocatable or allocated on any node, cpu, memory and pods first
+	ResourceNames []string
+	// Resources are allocation stats of every resource, CPU and Mem are the ones of cpu and memory
+	Resources map[string]Stats
+	// Utilization are allocation stats of every resource allocatable on any node normalised by node allocatable
+	Utilization map[string]Utilization
+	// Fragmentation is a stranded capacity and headroom of service pod shapes after scheduling
+	Fragmentation Fragmentation
+	CPU           Stats
+	Mem           Stats
 }
 
-// ListNodes lists cluster nodes from kubernetes-scheduler-simulator with advanced analytics
-func ListNodes(c *client.HTTPClient) error {
-	nodes, err := listNodes(c)
+// ListNodes lists cluster nodes from kubernetes-scheduler-simulator with advanced analytics,
+// groupBy is a grouping key of pods into services, see Analyze
+func ListNodes(ctx context.Context, c client.SimulatorClient, groupBy string) error {
+	a, err := Analyze(ctx, c, groupBy)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.ListPods()
+	return PrintAnalysis(a)
+}
+
+// Analyze fetches cluster nodes and pods from kubernetes-scheduler-simulator and calculates allocation stats.
+// groupBy is a grouping key of pods into services of fragmentation analysis: label:<key>, owner or namespace,
+// same as import pods filter groupBy, label:service if empty
+func Analyze(ctx context.Context, c client.SimulatorClient, groupBy string) (*Analysis, error) {
+	if groupBy == "" {
+		groupBy = _import.DefaultGroupBy
+	}
+
+	groupKey, err := _import.ParseGroupBy(groupBy)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	nodes, err := listNodes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.ListPods(ctx)
+	if err != nil {
+		return nil, err
 	}
 	if resp.StatusCode >= http.StatusMultipleChoices {
-		return fmt.Errorf("got scheduler response status: %d", resp.StatusCode)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("got scheduler response status: %d", resp.StatusCode)
 	}
 
 	b, _ := ioutil.ReadAll(resp.Body)
 
 	err = resp.Body.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	root, err := insaneJSON.DecodeBytes(b)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	pods := root.Dig("items")
 
-	//log.Printf("found pods: %d\n", len(pods.AsArray()))
-	//log.Printf("found nodes: %d\n", len(nodes))
-
-	prefillNodesWithPods(nodes, pods)
+	a := &Analysis{
+		Nodes:     nodes,
+		PodsCount: len(pods.AsArray()),
+	}
+	a.UnscheduledPods, a.RequestDiffs = prefillNodesWithPods(nodes, pods)
+	services, smallest := podShapes(pods, groupKey)
 
 	insaneJSON.Release(root)
 
-	minCPU, maxCPU := calculateCPUImbalance(nodes)
-	minMem, maxMem := calculateMemImbalance(nodes)
-	stddevCPU := stat.PopStdDev(nodesCPUAllocatedArray(nodes), nil)
-	stddevMem := stat.PopStdDev(nodesMemAllocatedGbArray(nodes), nil)
+	a.ResourceNames = resourceNames(nodes)
+	a.Resources = map[string]Stats{}
+	a.Utilization = map[string]Utilization{}
+
+	for _, name := range a.ResourceNames {
+		lower, higher := calculateImbalance(nodes, name)
+
+		a.Resources[name] = Stats{
+			Min:       lower,
+			Max:       higher,
+			Imbalance: higher - lower,
+			StdDev:    stat.PopStdDev(nodesAllocatedArray(nodes, name), nil),
+		}
+
+		if u, ok := calculateUtilization(nodes, name); ok {
+			a.Utilization[name] = u
+		}
+	}
+
+	a.CPU = a.Resources[resourceCPU]
+	a.Mem = a.Resources[resourceMemory]
+	a.Fragmentation = analyzeFragmentation(nodes, groupBy, services, smallest)
+
+	return a, nil
+}
+
+// PrintAnalysis logs imbalance stats and renders nodes chart to stdout
+func PrintAnalysis(a *Analysis) error {
+	for _, name := range a.ResourceNames {
+		s, unit := a.Resources[name], ResourceUnit(name)
+		log.Printf("Imbalance %s: %.2f, min=%.2f%s, max=%.2f%s, stddev=%.2f\n", resourceLabel(name), s.Imbalance, s.Min, unit, s.Max, unit, s.StdDev)
+	}
+
+	for _, name := range a.ResourceNames {
+		if u, ok := a.Utilization[name]; ok {
+			log.Printf("Utilization %s: imbalance=%.3f, min=%.3f, max=%.3f, stddev=%.3f, cv=%.3f, gini=%.3f, jain=%.3f\n",
+				resourceLabel(name), u.Imbalance, u.Min, u.Max, u.StdDev, u.CV, u.Gini, u.Jain)
+		}
+	}
+
+	if len(a.UnscheduledPods) > 0 {
+		log.Printf("Unscheduled pods: %d\n", len(a.UnscheduledPods))
+	}
+
+	if len(a.RequestDiffs) > 0 {
+		effective, naive := Resources{}, Resources{}
+		for _, d := range a.RequestDiffs {
+			effective.add(d.Effective)
+			naive.add(d.Naive)
+		}
+
+		log.Printf("Effective requests differ from containers sum for %d pods: CPU %+.2f, Mem %+.2fGiB\n", len(a.RequestDiffs),
+			ResourceValue(resourceCPU, effective[resourceCPU]-naive[resourceCPU]),
+			ResourceValue(resourceMemory, effective[resourceMemory]-naive[resourceMemory]))
+	}
+
+	f := a.Fragmentation
+	log.Printf("Free CPU: %.2f, stranded=%.2f on %d nodes, Free Mem: %.2fGiB, stranded=%.2fGiB on %d nodes\n",
+		f.FreeCPU, f.StrandedCPU, f.StrandedCPUNodes, f.FreeMemGb, f.StrandedMemGb, f.StrandedMemNodes)
+
+	if f.LargestCPUFit != nil {
+		log.Printf("Largest fitting pod: CPU %.2f with Mem %.2fGiB, Mem %.2fGiB with CPU %.2f\n",
+			ResourceValue(resourceCPU, f.LargestCPUFit[resourceCPU]), ResourceValue(resourceMemory, f.LargestCPUFit[resourceMemory]),
+			ResourceValue(resourceMemory, f.LargestMemFit[resourceMemory]), ResourceValue(resourceCPU, f.LargestMemFit[resourceCPU]))
+	}
 
-	log.Printf("Imbalance CPU: %.2f, min=%.2f, max=%.2f, stddev=%.2f\n", maxCPU-minCPU, minCPU, maxCPU, stddevCPU)
-	log.Printf("Imbalance Mem: %.2f, min=%.2fGb, max=%.2fGb, stddev=%.2f\n", maxMem-minMem, minMem, maxMem, stddevMem)
+	if len(f.Services) > 0 {
+		log.Printf("Services headroom, pods grouped by %s:\n", f.GroupBy)
+	}
+	for _, s := range f.Services {
+		log.Printf("Service %s: %d pods of CPU %.2f, Mem %.2fGiB, headroom %d\n", s.Service, s.Pods,
+			ResourceValue(resourceCPU, s.Shape[resourceCPU]), ResourceValue(resourceMemory, s.Shape[resourceMemory]), s.Headroom)
+	}
 
-	return nodesChart(nodes)
+	return nodesChart(a)
 }
 
-func listNodes(c *client.HTTPClient) (map[string]*Node, error) {
-	resp, err := c.ListNodes()
+// SortedNodes returns analysed nodes sorted by name
+func (a *Analysis) SortedNodes() []*Node {
+	return sortedNodes(a.Nodes)
+}
+
+func listNodes(ctx context.Context, c client.SimulatorClient) (map[string]*Node, error) {
+	resp, err := c.ListNodes(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode >= http.StatusMultipleChoices {
+		_ = resp.Body.Close()
 		return nil, fmt.Errorf("got scheduler response status: %d", resp.StatusCode)
 	}
 
@@ -99,17 +230,15 @@ func listNodes(c *client.HTTPClient) (map[string]*Node, error) {
 	nodesList := map[string]*Node{}
 
 	for _, node := range nodes {
-		nodeMemGb, err := memToGb(node.Dig("status").Dig("allocatable").Dig("memory").AsString())
-		if err != nil {
-			log.Printf("Can't parse node mem allocatable: %s, error: %s\n", node.Dig("status").Dig("allocatable").Dig("memory").AsString(), err)
+		n := &Node{
+			Name:        copyString(node.Dig("metadata").Dig("name").AsString()),
+			Allocatable: parseResources(node.Dig("status").Dig("allocatable")),
+			Allocated:   Resources{},
+			Pods:        []string{},
 		}
+		n.updateDerived()
 
-		nodesList[node.Dig("metadata").Dig("name").AsString()] = &Node{
-			Name:             node.Dig("metadata").Dig("name").AsString(),
-			AllocatableCores: node.Dig("status").Dig("allocatable").Dig("cpu").AsFloat(),
-			AllocatableMemGb: nodeMemGb,
-			Pods:             []string{},
-		}
+		nodesList[n.Name] = n
 	}
 
 	root = nil
@@ -118,9 +247,18 @@ func listNodes(c *client.HTTPClient) (map[string]*Node, error) {
 	return nodesList, nil
 }
 
-func prefillNodesWithPods(nodes map[string]*Node, pods *insaneJSON.Node) {
+// prefillNodesWithPods sums effective pods requests per node and returns names of pods not bound to any node
+// and scheduled pods which effective requests differ from sum of containers requests
+func prefillNodesWithPods(nodes map[string]*Node, pods *insaneJSON.Node) ([]string, []PodRequestDiff) {
+	unscheduled := []string{}
+	diffs := []PodRequestDiff{}
+
 	for _, pod := range pods.AsArray() {
 		nodeName := pod.Dig("spec").Dig("nodeName").AsString()
+		if nodeName == "" {
+			unscheduled = append(unscheduled, copyString(pod.Dig("metadata").Dig("name").AsString()))
+			continue
+		}
 
 		n, ok := nodes[nodeName]
 		if !ok {
@@ -128,46 +266,61 @@ func prefillNodesWithPods(nodes map[string]*Node, pods *insaneJSON.Node) {
 			continue
 		}
 
-		n.Pods = append(n.Pods, pod.Dig("metadata").Dig("name").AsString())
-
-		for _, container := range pod.Dig("spec").Dig("containers").AsArray() {
-			cpu, err := cpuToCores(container.Dig("resources").Dig("requests").Dig("cpu").AsString())
-			if err != nil {
-				log.Printf("Can't parse CPU requests: %s, error: %s\n", (container.Dig("resources").Dig("requests").Dig("cpu").AsString()), err)
-			}
+		podName := copyString(pod.Dig("metadata").Dig("name").AsString())
+		n.Pods = append(n.Pods, podName)
+		n.AllocatedPods++
 
-			n.AllocatedCores += cpu
-
-			mem, err := memToGb(container.Dig("resources").Dig("requests").Dig("memory").AsString())
-			if err != nil {
-				log.Printf("Can't parse memory requests: %s, error: %s\n", container.Dig("resources").Dig("requests").Dig("memory").AsString(), err)
-			}
+		effective, naive := podRequests(pod)
+		if !effective.equal(naive) {
+			diffs = append(diffs, PodRequestDiff{Pod: podName, Node: n.Name, Effective: effective, Naive: naive})
+		}
 
-			n.AllocatedMemGb += mem
+		if n.Allocated == nil {
+			n.Allocated = Resources{}
 		}
+		n.Allocated.add(effective)
+		n.Allocated[resourcePods]++
+		n.updateDerived()
 	}
+
+	return unscheduled, diffs
 }
 
-func nodesChart(nodes map[string]*Node) error {
+// copyString detaches string from insaneJSON decoder buffer, which is reused after Release
+func copyString(s string) string {
+	return string(append([]byte(nil), s...))
+}
+
+// nodesChart renders allocatable and allocated amounts of every resource per node
+func nodesChart(a *Analysis) error {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Node", "Allocatable CPU", "Allocated CPU", "Allocatable Mem, Gb", "Allocated Mem, Gb"})
 
-	headColor := tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor}
-	table.SetHeaderColor(headColor, headColor, headColor, headColor, headColor)
+	header := []string{"Node"}
+	for _, name := range a.ResourceNames {
+		label := resourceLabel(name)
+		if unit := ResourceUnit(name); unit != "" {
+			label += ", " + unit
+		}
+		header = append(header, "Allocatable "+label, "Allocated "+label)
+	}
+	table.SetHeader(header)
 
-	colColor := tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiBlackColor}
-	table.SetColumnColor(colColor, colColor, colColor, colColor, colColor)
+	headColors, colColors := make([]tablewriter.Colors, len(header)), make([]tablewriter.Colors, len(header))
+	for i := range header {
+		headColors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor}
+		colColors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiBlackColor}
+	}
+	table.SetHeaderColor(headColors...)
+	table.SetColumnColor(colColors...)
 
 	data := [][]string{}
 
-	for _, node := range nodes {
-		data = append(data, []string{
-			node.Name,
-			fmt.Sprintf("%.1f", node.AllocatableCores),
-			fmt.Sprintf("%.2f", node.AllocatedCores),
-			fmt.Sprintf("%.2f", node.AllocatableMemGb),
-			fmt.Sprintf("%.2f", node.AllocatedMemGb)},
-		)
+	for _, node := range a.SortedNodes() {
+		row := []string{node.Name}
+		for _, name := range a.ResourceNames {
+			row = append(row, formatResource(name, node.Allocatable[name]), formatResource(name, node.Allocated[name]))
+		}
+		data = append(data, row)
 	}
 
 	table.AppendBulk(data)
@@ -176,108 +329,46 @@ func nodesChart(nodes map[string]*Node) error {
 	return nil
 }
 
-func cpuToCores(v string) (float64, error) {
-	if v[len(v)-1:] == "m" {
-		cpuMillis, err := strconv.ParseFloat(v[:len(v)-1], 10)
-		if err != nil {
-			return 0, err
-		}
-
-		cpuCores := cpuMillis / 1000
-
-		return float64(cpuCores), nil
+// formatResource formats amount of resource in reported units, counts without fraction
+func formatResource(name string, v int64) string {
+	if name != resourceCPU && !isBytesResource(name) {
+		return fmt.Sprintf("%d", v)
 	}
 
-	cpuCores, err := strconv.ParseFloat(v, 10)
-	if err != nil {
-		return 0, err
-	}
-	return cpuCores, nil
+	return fmt.Sprintf("%.2f", ResourceValue(name, v))
 }
 
-func memToGb(v string) (float64, error) {
-	memStr := v
-	multiplicator := float64(1)
-
-	switch v[len(v)-2:] {
-	case "Ki":
-		memStr = v[:len(v)-2]
-		multiplicator = math.Pow(2, 30) / math.Pow(10, 3)
-	case "Mi":
-		memStr = v[:len(v)-2]
-		multiplicator = math.Pow(2, 30) / math.Pow(10, 6)
-	case "Gi":
-		memStr = v[:len(v)-2]
-		multiplicator = math.Pow(2, 30) / math.Pow(10, 9)
-	}
-
-	switch v[len(v)-1:] {
-	case "K":
-		memStr = v[:len(v)-1]
-		multiplicator = math.Pow(2, 20)
-	case "M":
-		memStr = v[:len(v)-1]
-		multiplicator = math.Pow(2, 10)
-	case "G":
-		memStr = v[:len(v)-1]
-		multiplicator = 1
-	}
-
-	mem, err := strconv.ParseFloat(memStr, 10)
-	if err != nil {
-		return 0, err
-	}
-
-	memGb := mem / multiplicator
-
-	return float64(memGb), nil
+// updateDerived recalculates cores and GiB from exact millicores and bytes
+func (n *Node) updateDerived() {
+	n.AllocatableCores = ResourceValue(resourceCPU, n.Allocatable[resourceCPU])
+	n.AllocatedCores = ResourceValue(resourceCPU, n.Allocated[resourceCPU])
+	n.AllocatableMemGb = ResourceValue(resourceMemory, n.Allocatable[resourceMemory])
+	n.AllocatedMemGb = ResourceValue(resourceMemory, n.Allocated[resourceMemory])
 }
 
-func calculateCPUImbalance(nodes map[string]*Node) (lowerBound, higherBound float64) {
+// calculateImbalance returns min and max allocated amounts of resource across nodes in reported units
+func calculateImbalance(nodes map[string]*Node, name string) (lowerBound, higherBound float64) {
 	var isInited bool
 
 	for _, node := range nodes {
-		if !isInited || lowerBound > node.AllocatedCores {
-			lowerBound = node.AllocatedCores
-			isInited = true
-		}
-		if higherBound < node.AllocatedCores {
-			higherBound = node.AllocatedCores
-		}
-	}
+		allocated := ResourceValue(name, node.Allocated[name])
 
-	return
-}
-
-func calculateMemImbalance(nodes map[string]*Node) (lowerBound, higherBound float64) {
-	var isInited bool
-
-	for _, node := range nodes {
-		if !isInited || lowerBound > node.AllocatedMemGb {
-			lowerBound = node.AllocatedMemGb
+		if !isInited || lowerBound > allocated {
+			lowerBound = allocated
 			isInited = true
 		}
-		if higherBound < node.AllocatedMemGb {
-			higherBound = node.AllocatedMemGb
+		if higherBound < allocated {
+			higherBound = allocated
 		}
 	}
 
 	return
 }
 
-func nodesCPUAllocatedArray(nodes map[string]*Node) []float64 {
-	out := []float64{}
-	for _, node := range nodes {
-		out = append(out, node.AllocatedCores)
-	}
-
-	return out
-}
-
-func nodesMemAllocatedGbArray(nodes map[string]*Node) []float64 {
+func nodesAllocatedArray(nodes map[string]*Node, name string) []float64 {
 	out := []float64{}
 	for _, node := range nodes {
-		out = append(out, node.AllocatedMemGb)
+		out = append(out, ResourceValue(name, node.Allocated[name]))
 	}
 
 	return out
@@ -0,0 +1,21 @@
+package cluster
+
+import (
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+)
+
+// PodRequestDiff is a scheduled pod which effective requests differ from sum of its containers requests
+type PodRequestDiff struct {
+	Pod       string    `json:"pod"`
+	Node      string    `json:"node"`
+	Effective Resources `json:"effective"`
+	Naive     Resources `json:"naive"`
+}
+
+// podRequests returns effective pod requests the way NodeResourcesFit plugin computes them and naive sum of containers requests,
+// the same requests are used by import filters, see _import.PodRequests
+func podRequests(pod *insaneJSON.Node) (effective, naive Resources) {
+	return _import.PodRequests(pod)
+}
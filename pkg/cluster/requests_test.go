@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+)
+
+func Test_podRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		effective Resources
+		naive     Resources
+	}{
+		{
+			"containers",
+			`{"containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}, {"resources": {"requests": {"cpu": "250m"}}}]}`,
+			Resources{"cpu": 750, "memory": 1 << 30},
+			Resources{"cpu": 750, "memory": 1 << 30},
+		},
+		{
+			"no requests",
+			`{"containers": [{"name": "app"}]}`,
+			Resources{},
+			Resources{},
+		},
+		{
+			"init container above containers",
+			`{"initContainers": [{"resources": {"requests": {"cpu": "2", "memory": "512Mi"}}}, {"resources": {"requests": {"cpu": "1"}}}],
+			  "containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}`,
+			Resources{"cpu": 2000, "memory": 1 << 30},
+			Resources{"cpu": 500, "memory": 1 << 30},
+		},
+		{
+			"overhead",
+			`{"containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}], "overhead": {"cpu": "250m", "memory": "120Mi"}}`,
+			Resources{"cpu": 750, "memory": 1<<30 + 120<<20},
+			Resources{"cpu": 500, "memory": 1 << 30},
+		},
+		{
+			"extended resources",
+			`{"containers": [
+				{"resources": {"requests": {"cpu": "1", "ephemeral-storage": "10Gi", "hugepages-2Mi": "256Mi", "nvidia.com/gpu": "2"}}},
+				{"resources": {"requests": {"nvidia.com/gpu": "1"}}}
+			  ]}`,
+			Resources{"cpu": 1000, "ephemeral-storage": 10 << 30, "hugepages-2Mi": 256 << 20, "nvidia.com/gpu": 3},
+			Resources{"cpu": 1000, "ephemeral-storage": 10 << 30, "hugepages-2Mi": 256 << 20, "nvidia.com/gpu": 3},
+		},
+		{
+			"sidecars",
+			`{"initContainers": [
+				{"resources": {"requests": {"cpu": "1"}}},
+				{"restartPolicy": "Always", "resources": {"requests": {"cpu": "100m", "memory": "64Mi"}}},
+				{"resources": {"requests": {"cpu": "1500m"}}}
+			  ],
+			  "containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}`,
+			// sidecar runs next to containers and the last init container: max(500m + 100m, 1500m + 100m)
+			Resources{"cpu": 1600, "memory": 1<<30 + 64<<20},
+			Resources{"cpu": 500, "memory": 1 << 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := insaneJSON.DecodeString(`{"spec": ` + tt.spec + `}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer insaneJSON.Release(root)
+
+			effective, naive := podRequests(root.Node)
+			if !effective.equal(tt.effective) {
+				t.Errorf("podRequests() effective = %+v, want %+v", effective, tt.effective)
+			}
+			if !naive.equal(tt.naive) {
+				t.Errorf("podRequests() naive = %+v, want %+v", naive, tt.naive)
+			}
+		})
+	}
+}
+
+func Test_prefillNodesWithPodsRequestDiffs(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"items": [
+		{"metadata": {"name": "plain"}, "spec": {"nodeName": "node1", "containers": [{"resources": {"requests": {"cpu": "1"}}}]}},
+		{"metadata": {"name": "migrating"}, "spec": {"nodeName": "node1", "initContainers": [{"resources": {"requests": {"cpu": "3"}}}], "containers": [{"resources": {"requests": {"cpu": "1"}}}]}},
+		{"metadata": {"name": "pending"}, "spec": {"initContainers": [{"resources": {"requests": {"cpu": "3"}}}]}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insaneJSON.Release(root)
+
+	nodes := map[string]*Node{"node1": {Name: "node1"}}
+	unscheduled, diffs := prefillNodesWithPods(nodes, root.Dig("items"))
+
+	if len(unscheduled) != 1 || unscheduled[0] != "pending" {
+		t.Errorf("prefillNodesWithPods() unscheduled = %v, want [pending]", unscheduled)
+	}
+	if nodes["node1"].Allocated["cpu"] != 4000 || nodes["node1"].Allocated["pods"] != 2 || nodes["node1"].AllocatedCores != 4 {
+		t.Errorf("prefillNodesWithPods() node1 = %+v, want 4 cores of effective requests", nodes["node1"])
+	}
+
+	want := PodRequestDiff{Pod: "migrating", Node: "node1", Effective: Resources{"cpu": 3000}, Naive: Resources{"cpu": 1000}}
+	if len(diffs) != 1 || !reflect.DeepEqual(diffs[0], want) {
+		t.Errorf("prefillNodesWithPods() diffs = %+v, want [%+v]", diffs, want)
+	}
+}
@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"sort"
+	"strings"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+)
+
+const (
+	resourceCPU    = "cpu"
+	resourceMemory = "memory"
+	resourcePods   = "pods"
+)
+
+// Resources are exact amounts of resources by name: cpu in millicores, memory, ephemeral-storage and hugepages
+// in bytes, pods and extended resources in units
+type Resources map[string]int64
+
+func (r Resources) add(o Resources) {
+	for name, v := range o {
+		r[name] += v
+	}
+}
+
+func (r Resources) max(o Resources) {
+	for name, v := range o {
+		if v > r[name] {
+			r[name] = v
+		}
+	}
+}
+
+func (r Resources) clone() Resources {
+	out := make(Resources, len(r))
+	out.add(r)
+
+	return out
+}
+
+// equal compares amounts of resources, missing resource equals zero amount
+func (r Resources) equal(o Resources) bool {
+	for name, v := range r {
+		if o[name] != v {
+			return false
+		}
+	}
+	for name, v := range o {
+		if r[name] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseResources parses resource list of requests or allocatable, see _import.ParseResources
+func parseResources(list *insaneJSON.Node) Resources {
+	return _import.ParseResources(list)
+}
+
+// ResourceValue converts exact amount of resource to reported units: cpu to cores, bytes to GiB, other resources as is
+func ResourceValue(name string, v int64) float64 {
+	switch {
+	case name == resourceCPU:
+		return float64(v) / 1000
+	case isBytesResource(name):
+		return float64(v) / (1 << 30)
+	}
+
+	return float64(v)
+}
+
+// ResourceUnit returns unit of reported resource values, empty for cores and counts
+func ResourceUnit(name string) string {
+	if isBytesResource(name) {
+		return "GiB"
+	}
+
+	return ""
+}
+
+func isBytesResource(name string) bool {
+	return name == resourceMemory || name == "ephemeral-storage" || strings.HasPrefix(name, "hugepages-")
+}
+
+// resourceLabel returns short resource label of tables and logs
+func resourceLabel(name string) string {
+	switch name {
+	case resourceCPU:
+		return "CPU"
+	case resourceMemory:
+		return "Mem"
+	}
+
+	return name
+}
+
+// resourceNames returns names of resources allocatable or allocated on any node: cpu, memory and pods first,
+// then the rest sorted by name
+func resourceNames(nodes map[string]*Node) []string {
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		for name := range node.Allocatable {
+			seen[name] = true
+		}
+		for name := range node.Allocated {
+			seen[name] = true
+		}
+	}
+
+	names := []string{}
+	for _, name := range []string{resourceCPU, resourceMemory, resourcePods} {
+		if seen[name] {
+			names = append(names, name)
+			delete(seen, name)
+		}
+	}
+
+	rest := make([]string, 0, len(seen))
+	for name := range seen {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+
+	return append(names, rest...)
+}
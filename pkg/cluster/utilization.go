@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// Utilization is a resource allocation spread across nodes normalised by node allocatable: stats of allocated/allocatable
+// ratios of nodes with the resource, comparable across nodes of different sizes
+type Utilization struct {
+	Stats
+	// CV is a coefficient of variation of ratios, stddev divided by mean
+	CV float64 `json:"cv"`
+	// Gini is a Gini coefficient of ratios, 0 - equal utilization, close to 1 - all allocated on a single node
+	Gini float64 `json:"gini"`
+	// Jain is a Jain's fairness index of ratios, 1 - equal utilization, 1/n - all allocated on a single node
+	Jain float64 `json:"jain"`
+}
+
+// calculateUtilization returns utilization stats of resource, false if no node has the resource allocatable
+func calculateUtilization(nodes map[string]*Node, name string) (Utilization, bool) {
+	ratios := nodesUtilizationArray(nodes, name)
+	if len(ratios) == 0 {
+		return Utilization{}, false
+	}
+
+	sort.Float64s(ratios)
+
+	u := Utilization{
+		Stats: Stats{
+			Min:       ratios[0],
+			Max:       ratios[len(ratios)-1],
+			Imbalance: ratios[len(ratios)-1] - ratios[0],
+			StdDev:    stat.PopStdDev(ratios, nil),
+		},
+		Gini: gini(ratios),
+		Jain: jain(ratios),
+	}
+	if mean := stat.Mean(ratios, nil); mean > 0 {
+		u.CV = u.StdDev / mean
+	}
+
+	return u, true
+}
+
+// nodesUtilizationArray returns allocated/allocatable ratios of resource of nodes with non-zero allocatable
+func nodesUtilizationArray(nodes map[string]*Node, name string) []float64 {
+	out := []float64{}
+	for _, node := range nodes {
+		if allocatable := node.Allocatable[name]; allocatable > 0 {
+			out = append(out, float64(node.Allocated[name])/float64(allocatable))
+		}
+	}
+
+	return out
+}
+
+// gini calculates Gini coefficient of sorted non-negative values, 0 if all values are zero
+func gini(sorted []float64) float64 {
+	var sum, weighted float64
+	for i, v := range sorted {
+		sum += v
+		weighted += float64(i+1) * v
+	}
+
+	if sum == 0 {
+		return 0
+	}
+
+	n := float64(len(sorted))
+
+	return math.Max(0, 2*weighted/(n*sum)-(n+1)/n)
+}
+
+// jain calculates Jain's fairness index of non-negative values, 1 if all values are zero
+func jain(values []float64) float64 {
+	var sum, squares float64
+	for _, v := range values {
+		sum += v
+		squares += v * v
+	}
+
+	if squares == 0 {
+		return 1
+	}
+
+	return sum * sum / (float64(len(values)) * squares)
+}
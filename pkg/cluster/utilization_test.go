@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_gini(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"equal", []float64{0.5, 0.5, 0.5, 0.5}, 0},
+		{"zeros", []float64{0, 0}, 0},
+		{"single node", []float64{0, 0, 0, 1}, 0.75},
+		{"spread", []float64{0.1, 0.2, 0.3, 0.4}, 0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gini(tt.values); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("gini() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_jain(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"equal", []float64{0.5, 0.5, 0.5, 0.5}, 1},
+		{"zeros", []float64{0, 0}, 1},
+		{"single node", []float64{0, 0, 0, 1}, 0.25},
+		{"spread", []float64{0.1, 0.2, 0.3, 0.4}, 1.0 / 1.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jain(tt.values); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("jain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_calculateUtilization(t *testing.T) {
+	nodes := map[string]*Node{
+		"small": {Allocatable: Resources{"cpu": 4000, "memory": 8 << 30}, Allocated: Resources{"cpu": 2000, "memory": 2 << 30}},
+		"large": {Allocatable: Resources{"cpu": 16000, "memory": 64 << 30}, Allocated: Resources{"cpu": 8000, "memory": 48 << 30}},
+		"gpu":   {Allocatable: Resources{"cpu": 8000, "nvidia.com/gpu": 4}, Allocated: Resources{"cpu": 4000, "nvidia.com/gpu": 1}},
+	}
+
+	// same share of cpu is allocated on every node whatever its size
+	cpu, ok := calculateUtilization(nodes, "cpu")
+	if !ok || cpu.Imbalance != 0 || cpu.Min != 0.5 || cpu.CV != 0 || cpu.Gini != 0 || cpu.Jain != 1 {
+		t.Errorf("calculateUtilization() cpu = %+v, %v", cpu, ok)
+	}
+
+	mem, ok := calculateUtilization(nodes, "memory")
+	if !ok || mem.Min != 0.25 || mem.Max != 0.75 || mem.Imbalance != 0.5 || mem.StdDev != 0.25 || mem.CV != 0.5 || mem.Gini != 0.25 || mem.Jain != 0.8 {
+		t.Errorf("calculateUtilization() memory = %+v, %v", mem, ok)
+	}
+
+	if gpu, ok := calculateUtilization(nodes, "nvidia.com/gpu"); !ok || gpu.Max != 0.25 || gpu.Jain != 1 {
+		t.Errorf("calculateUtilization() gpu = %+v, %v", gpu, ok)
+	}
+
+	if _, ok := calculateUtilization(nodes, "hugepages-1Gi"); ok {
+		t.Errorf("calculateUtilization() hugepages-1Gi ok = true, want false")
+	}
+}
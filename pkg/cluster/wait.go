@@ -0,0 +1,175 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+)
+
+// WaitOptions configures waiting for scheduling to settle
+type WaitOptions struct {
+	// ExpectedPods is a number of imported pods which should be seen in cluster
+	ExpectedPods int
+	// Timeout is a max time to wait for scheduling
+	Timeout time.Duration
+	// PollInterval is an interval between pods listings
+	PollInterval time.Duration
+	// StablePolls is a number of consecutive polls with the same pods progress (total, scheduled and unschedulable counts)
+	// required to consider scheduling settled
+	StablePolls int
+}
+
+// PendingPodsError is returned when pods are still pending after wait timeout
+type PendingPodsError struct {
+	Timeout time.Duration
+	Pods    []string
+	// Missing is a number of expected pods which are not seen in cluster at all
+	Missing int
+}
+
+func (e *PendingPodsError) Error() string {
+	msg := fmt.Sprintf("scheduling not settled after %s, pending pods (%d): %s", e.Timeout, len(e.Pods), strings.Join(e.Pods, ", "))
+	if e.Missing > 0 {
+		msg += fmt.Sprintf(", missing pods: %d", e.Missing)
+	}
+
+	return msg
+}
+
+// schedulingState is a snapshot of pods scheduling progress
+type schedulingState struct {
+	total         int
+	scheduled     int
+	unschedulable int
+	pending       []string
+}
+
+// schedulingProgress is compared between polls, scheduling is settled only when none of the counts changes
+type schedulingProgress struct {
+	total         int
+	scheduled     int
+	unschedulable int
+}
+
+func (s *schedulingState) progress() schedulingProgress {
+	return schedulingProgress{total: s.total, scheduled: s.scheduled, unschedulable: s.unschedulable}
+}
+
+// WaitForScheduling polls kubernetes-scheduler-simulator pods until every pod is either bound to a node
+// or marked unschedulable and pods progress has stopped changing
+func WaitForScheduling(ctx context.Context, c client.SimulatorClient, opts WaitOptions) error {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.StablePolls <= 0 {
+		opts.StablePolls = 1
+	}
+
+	deadline := time.Now().Add(opts.Timeout)
+	lastProgress := schedulingProgress{total: -1}
+	stablePolls := 0
+
+	for {
+		state, err := fetchSchedulingState(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		if progress := state.progress(); progress == lastProgress {
+			stablePolls++
+		} else {
+			stablePolls = 0
+			lastProgress = progress
+		}
+
+		if state.total >= opts.ExpectedPods && len(state.pending) == 0 && stablePolls >= opts.StablePolls {
+			if state.unschedulable > 0 {
+				log.Printf("Scheduling settled: scheduled %d, unschedulable %d\n", state.scheduled, state.unschedulable)
+			}
+			return nil
+		}
+
+		if time.Now().Add(opts.PollInterval).After(deadline) {
+			pendingErr := &PendingPodsError{Timeout: opts.Timeout, Pods: state.pending}
+			if state.total < opts.ExpectedPods {
+				pendingErr.Missing = opts.ExpectedPods - state.total
+			}
+			return pendingErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+func fetchSchedulingState(ctx context.Context, c client.SimulatorClient) (*schedulingState, error) {
+	resp, err := c.ListPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("got scheduler response status: %d", resp.StatusCode)
+	}
+
+	b, _ := ioutil.ReadAll(resp.Body)
+
+	err = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := insaneJSON.DecodeBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	defer insaneJSON.Release(root)
+
+	return newSchedulingState(root.Dig("items")), nil
+}
+
+func newSchedulingState(pods *insaneJSON.Node) *schedulingState {
+	state := &schedulingState{pending: []string{}}
+
+	for _, pod := range pods.AsArray() {
+		state.total++
+
+		switch {
+		case pod.Dig("spec").Dig("nodeName").AsString() != "":
+			state.scheduled++
+		case isUnschedulable(pod):
+			state.unschedulable++
+		default:
+			state.pending = append(state.pending, copyString(pod.Dig("metadata").Dig("name").AsString()))
+		}
+	}
+
+	sort.Strings(state.pending)
+
+	return state
+}
+
+// isUnschedulable checks PodScheduled condition set by scheduler when pod doesn't fit any node
+func isUnschedulable(pod *insaneJSON.Node) bool {
+	for _, cond := range pod.Dig("status").Dig("conditions").AsArray() {
+		if cond.Dig("type").AsString() == "PodScheduled" &&
+			cond.Dig("status").AsString() == "False" &&
+			cond.Dig("reason").AsString() == "Unschedulable" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client/fake"
+)
+
+func Test_newSchedulingState(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"items": [
+		{"metadata": {"name": "scheduled"}, "spec": {"nodeName": "node1"}},
+		{"metadata": {"name": "unschedulable"}, "spec": {}, "status": {"conditions": [
+			{"type": "PodScheduled", "status": "False", "reason": "Unschedulable"}
+		]}},
+		{"metadata": {"name": "pending-b"}, "spec": {}},
+		{"metadata": {"name": "pending-a"}, "spec": {}, "status": {"conditions": [
+			{"type": "PodScheduled", "status": "True"}
+		]}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insaneJSON.Release(root)
+
+	state := newSchedulingState(root.Dig("items"))
+
+	if state.total != 4 || state.scheduled != 1 || state.unschedulable != 1 {
+		t.Errorf("newSchedulingState() got = %+v", state)
+	}
+
+	if want := []string{"pending-a", "pending-b"}; !reflect.DeepEqual(state.pending, want) {
+		t.Errorf("newSchedulingState() pending = %v, want %v", state.pending, want)
+	}
+}
+
+func TestWaitForScheduling(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	c := s.Client()
+
+	for _, obj := range []string{`{"metadata": {"name": "pod1"}}`, `{"metadata": {"name": "pod2"}}`} {
+		resp, err := c.ApplyPods(context.Background(), []byte(obj))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	opts := WaitOptions{ExpectedPods: 2, Timeout: time.Second, PollInterval: time.Millisecond, StablePolls: 2}
+	if err := WaitForScheduling(context.Background(), c, opts); err != nil {
+		t.Fatalf("WaitForScheduling() error = %v", err)
+	}
+
+	opts = WaitOptions{ExpectedPods: 5, PollInterval: time.Millisecond}
+	err := WaitForScheduling(context.Background(), c, opts)
+
+	var pendingErr *PendingPodsError
+	if !errors.As(err, &pendingErr) || pendingErr.Missing != 3 {
+		t.Errorf("WaitForScheduling() error = %v, want 3 missing pods", err)
+	}
+}
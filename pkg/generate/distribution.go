@@ -0,0 +1,169 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Distribution types
+const (
+	Fixed     = "fixed"
+	Uniform   = "uniform"
+	LogNormal = "lognormal"
+	Empirical = "empirical"
+)
+
+// Distribution describes a random quantity: cpu, memory or replicas count. Values are kubernetes quantities,
+// e.g. 250m, 1.5, 512Mi
+type Distribution struct {
+	// Type is fixed, uniform, lognormal or empirical, fixed by default
+	Type string `yaml:"type"`
+	// Value is a value of fixed distribution
+	Value string `yaml:"value"`
+	// Min and Max are bounds of uniform distribution and optional clamps of lognormal one
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+	// Median and Sigma are lognormal distribution parameters, sigma is a standard deviation of value logarithm
+	Median string  `yaml:"median"`
+	Sigma  float64 `yaml:"sigma"`
+	// Values are samples of empirical distribution, each is drawn with equal probability
+	Values []string `yaml:"values"`
+	// File is a pods file or directory of manifests empirical distribution is fitted from,
+	// samples are pod requests of the distributed resource
+	File string `yaml:"file"`
+}
+
+// sampler draws values of a compiled distribution in base units: cores, bytes or items
+type sampler func(r *rand.Rand) float64
+
+// compile validates distribution and returns its sampler, res is a resource name for file samples, empty if not allowed.
+// Files are not read if files cache is nil
+func (d Distribution) compile(res string, files *fileCache) (sampler, error) {
+	switch d.Type {
+	case "", Fixed:
+		v, err := parseFloat("value", d.Value)
+		if err != nil {
+			return nil, err
+		}
+		return func(*rand.Rand) float64 { return v }, nil
+
+	case Uniform:
+		min, err := parseFloat("min", d.Min)
+		if err != nil {
+			return nil, err
+		}
+		max, err := parseFloat("max", d.Max)
+		if err != nil {
+			return nil, err
+		}
+		if max < min {
+			return nil, fmt.Errorf("max %s < min %s", d.Max, d.Min)
+		}
+		return func(r *rand.Rand) float64 { return min + r.Float64()*(max-min) }, nil
+
+	case LogNormal:
+		median, err := parseFloat("median", d.Median)
+		if err != nil {
+			return nil, err
+		}
+		if median <= 0 || d.Sigma < 0 {
+			return nil, fmt.Errorf("median must be positive and sigma must not be negative")
+		}
+		clamp, err := d.clamp()
+		if err != nil {
+			return nil, err
+		}
+		mu := math.Log(median)
+		return func(r *rand.Rand) float64 { return clamp(math.Exp(mu + d.Sigma*r.NormFloat64())) }, nil
+
+	case Empirical:
+		values, err := d.empirical(res, files)
+		if err != nil {
+			return nil, err
+		}
+		return func(r *rand.Rand) float64 { return values[r.Intn(len(values))] }, nil
+	}
+
+	return nil, fmt.Errorf("unknown distribution type %q, want fixed, uniform, lognormal or empirical", d.Type)
+}
+
+// isZero reports if distribution is not set
+func (d Distribution) isZero() bool {
+	return d.Type == "" && d.Value == "" && d.Min == "" && d.Max == "" && d.Median == "" && d.Sigma == 0 && len(d.Values) == 0 && d.File == ""
+}
+
+// empirical returns samples listed inline or read from file
+func (d Distribution) empirical(res string, files *fileCache) ([]float64, error) {
+	if d.File != "" && len(d.Values) > 0 {
+		return nil, fmt.Errorf("values and file are mutually exclusive")
+	}
+
+	if d.File == "" {
+		if len(d.Values) == 0 {
+			return nil, fmt.Errorf("values or file is required")
+		}
+
+		values := make([]float64, 0, len(d.Values))
+		for i, s := range d.Values {
+			v, err := parseFloat(fmt.Sprintf("values[%d]", i), s)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	if res == "" {
+		return nil, fmt.Errorf("file: samples from file are available for cpu and memory only")
+	}
+	if files == nil {
+		// validation only, file is read on generation
+		return []float64{0}, nil
+	}
+
+	values, err := files.samples(d.File, res)
+	if err != nil {
+		return nil, fmt.Errorf("file: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("file: no pods with %s requests in %s", res, d.File)
+	}
+
+	return values, nil
+}
+
+// clamp returns func limiting values to optional min and max
+func (d Distribution) clamp() (func(v float64) float64, error) {
+	min, max := math.Inf(-1), math.Inf(1)
+
+	var err error
+	if d.Min != "" {
+		if min, err = parseFloat("min", d.Min); err != nil {
+			return nil, err
+		}
+	}
+	if d.Max != "" {
+		if max, err = parseFloat("max", d.Max); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(v float64) float64 { return math.Max(min, math.Min(max, v)) }, nil
+}
+
+func parseFloat(field, v string) (float64, error) {
+	if v == "" {
+		return 0, fmt.Errorf("%s is required", field)
+	}
+
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, err)
+	}
+
+	return q.AsApproximateFloat64(), nil
+}
@@ -0,0 +1,414 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/stat"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// ModelVersion is a version of workload model format, models of newer versions are rejected
+const ModelVersion = 1
+
+// histogram upper bounds of pod requests and service replicas
+var (
+	cpuBounds      = []string{"10m", "25m", "50m", "100m", "250m", "500m", "1", "2", "4", "8", "16", "32"}
+	memoryBounds   = []string{"64Mi", "128Mi", "256Mi", "512Mi", "1Gi", "2Gi", "4Gi", "8Gi", "16Gi", "32Gi", "64Gi"}
+	replicasBounds = []string{"1", "2", "3", "5", "10", "20", "50", "100"}
+)
+
+// Model is a workload model fitted from a pods dump: descriptive statistics of services and pods
+// and distinct service shapes generated workloads draw services from
+type Model struct {
+	Version int `yaml:"version"`
+	// GroupBy is a grouping key pods were grouped into services by, pods without group are single replica services
+	GroupBy  string `yaml:"groupBy"`
+	Pods     int    `yaml:"pods"`
+	Services int    `yaml:"services"`
+	// Replicas is a histogram of services by replicas count
+	Replicas []Bucket `yaml:"replicas"`
+	// CPU and Memory are histograms of pods by requests, sum of containers requests
+	CPU    []Bucket `yaml:"cpu"`
+	Memory []Bucket `yaml:"memory"`
+	// MemoryPerCPU are quantiles of memory requests per requested cpu core of pods requesting cpu
+	MemoryPerCPU Quantiles `yaml:"memoryPerCPU"`
+	// CPUMemoryCorrelation is a Pearson correlation of logarithms of cpu and memory requests of pods requesting both
+	CPUMemoryCorrelation float64 `yaml:"cpuMemoryCorrelation"`
+	// Features are fractions of pods with scheduling constraints
+	Features Features `yaml:"features"`
+	// Shapes are distinct service shapes, most common first
+	Shapes []Shape `yaml:"shapes"`
+}
+
+// Bucket is a histogram bucket
+type Bucket struct {
+	// Le is an inclusive upper bound of bucket, +Inf for the last one
+	Le    string `yaml:"le"`
+	Count int    `yaml:"count"`
+}
+
+// Quantiles are 10th, 50th and 90th percentiles
+type Quantiles struct {
+	P10 string `yaml:"p10"`
+	P50 string `yaml:"p50"`
+	P90 string `yaml:"p90"`
+}
+
+// Features are fractions of pods with pod affinity, pod anti-affinity, node affinity, node selector,
+// tolerations and topology spread constraints
+type Features struct {
+	Affinity     float64 `yaml:"affinity"`
+	AntiAffinity float64 `yaml:"antiAffinity"`
+	NodeAffinity float64 `yaml:"nodeAffinity"`
+	NodeSelector float64 `yaml:"nodeSelector"`
+	Tolerations  float64 `yaml:"tolerations"`
+	Spread       float64 `yaml:"spread"`
+}
+
+// Shape is a service shape: replicas, pod requests and constraints which are reproducible on any fleet.
+// Node affinity, node selectors and pod affinity refer to labels of the source cluster and are not kept
+type Shape struct {
+	// Services is a number of services of this shape
+	Services     int      `yaml:"services"`
+	Replicas     int      `yaml:"replicas"`
+	CPU          string   `yaml:"cpu"`
+	Memory       string   `yaml:"memory"`
+	AntiAffinity bool     `yaml:"antiAffinity,omitempty"`
+	Spread       bool     `yaml:"spread,omitempty"`
+	Tolerations  []string `yaml:"tolerations,omitempty"`
+}
+
+// model is a compiled Model
+type model struct {
+	shapes []shape
+	total  int
+}
+
+type shape struct {
+	Shape
+	cpu, memory float64
+	tolerations []corev1.Toleration
+}
+
+// Fit builds workload model of pods from source passing filter, pods are grouped into services by filter.GroupBy
+func Fit(src source.Source, filter _import.PodFilter) (*Model, error) {
+	type service struct {
+		first *corev1.Pod
+		pods  int
+	}
+
+	var (
+		services []*service
+		byGroup  = map[string]*service{}
+		pods     []*corev1.Pod
+	)
+
+	err := filter.Each(src, func(b []byte, group string) error {
+		pod := &corev1.Pod{}
+		if err := json.Unmarshal(b, pod); err != nil {
+			return fmt.Errorf("pod #%d: %w", len(pods), err)
+		}
+		pods = append(pods, pod)
+
+		s, ok := byGroup[group]
+		if !ok || group == "" {
+			s = &service{first: pod}
+			services = append(services, s)
+			if group != "" {
+				byGroup[group] = s
+			}
+		}
+		s.pods++
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pods to fit model")
+	}
+
+	m := &Model{
+		Version:  ModelVersion,
+		GroupBy:  filter.GroupBy,
+		Pods:     len(pods),
+		Services: len(services),
+	}
+	if m.GroupBy == "" {
+		m.GroupBy = _import.DefaultGroupBy
+	}
+
+	var (
+		cpus, memories   []float64
+		logCPU, logMem   []float64
+		memoryPerCPU     []float64
+		features         [6]int
+		replicasPerGroup []float64
+	)
+
+	for _, pod := range pods {
+		cpu, memory := podRequests(pod)
+		cpus = append(cpus, cpu)
+		memories = append(memories, memory)
+
+		if cpu > 0 {
+			memoryPerCPU = append(memoryPerCPU, memory/cpu)
+		}
+		if cpu > 0 && memory > 0 {
+			logCPU = append(logCPU, math.Log(cpu))
+			logMem = append(logMem, math.Log(memory))
+		}
+
+		for i, has := range podFeatures(pod) {
+			if has {
+				features[i]++
+			}
+		}
+	}
+
+	shapes := map[string]*Shape{}
+	for _, s := range services {
+		replicasPerGroup = append(replicasPerGroup, float64(s.pods))
+
+		sh := serviceShape(s.first, s.pods)
+		key := fmt.Sprintf("%d/%s/%s/%t/%t/%s", sh.Replicas, sh.CPU, sh.Memory, sh.AntiAffinity, sh.Spread, strings.Join(sh.Tolerations, ","))
+		if existing, ok := shapes[key]; ok {
+			existing.Services++
+			continue
+		}
+		sh.Services = 1
+		shapes[key] = &sh
+	}
+
+	for _, sh := range shapes {
+		m.Shapes = append(m.Shapes, *sh)
+	}
+	sort.Slice(m.Shapes, func(i, j int) bool {
+		a, b := m.Shapes[i], m.Shapes[j]
+		if a.Services != b.Services {
+			return a.Services > b.Services
+		}
+		if a.Replicas != b.Replicas {
+			return a.Replicas > b.Replicas
+		}
+		cpuA, cpuB := resource.MustParse(a.CPU), resource.MustParse(b.CPU)
+		if c := cpuA.Cmp(cpuB); c != 0 {
+			return c < 0
+		}
+		memA, memB := resource.MustParse(a.Memory), resource.MustParse(b.Memory)
+		return memA.Cmp(memB) < 0
+	})
+
+	m.Replicas = histogram(replicasPerGroup, replicasBounds)
+	m.CPU = histogram(cpus, cpuBounds)
+	m.Memory = histogram(memories, memoryBounds)
+	m.MemoryPerCPU = quantiles(memoryPerCPU)
+	if len(logCPU) > 1 {
+		if c := stat.Correlation(logCPU, logMem, nil); !math.IsNaN(c) {
+			m.CPUMemoryCorrelation = math.Round(c*1000) / 1000
+		}
+	}
+
+	fraction := func(n int) float64 {
+		return math.Round(float64(n)/float64(len(pods))*1000) / 1000
+	}
+	m.Features = Features{
+		Affinity:     fraction(features[0]),
+		AntiAffinity: fraction(features[1]),
+		NodeAffinity: fraction(features[2]),
+		NodeSelector: fraction(features[3]),
+		Tolerations:  fraction(features[4]),
+		Spread:       fraction(features[5]),
+	}
+
+	return m, nil
+}
+
+// LoadModel reads workload model from YAML file
+func LoadModel(filePath string) (*Model, error) {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Model{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(contents))
+	dec.KnownFields(true)
+	if err = dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	if m.Version > ModelVersion {
+		return nil, fmt.Errorf("%s: unsupported model version %d, max supported is %d", filePath, m.Version, ModelVersion)
+	}
+
+	return m, nil
+}
+
+// WriteFile writes model to YAML file
+func (m *Model) WriteFile(filePath string) error {
+	buf := &bytes.Buffer{}
+
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, buf.Bytes(), 0644)
+}
+
+func (m *Model) compile() (*model, error) {
+	if len(m.Shapes) == 0 {
+		return nil, fmt.Errorf("model has no shapes")
+	}
+
+	out := &model{}
+	for i, sh := range m.Shapes {
+		cpu, err := parseFloat("cpu", sh.CPU)
+		if err != nil {
+			return nil, fmt.Errorf("shapes[%d]: %w", i, err)
+		}
+		memory, err := parseFloat("memory", sh.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("shapes[%d]: %w", i, err)
+		}
+		tolerations, err := parseTolerations(sh.Tolerations)
+		if err != nil {
+			return nil, fmt.Errorf("shapes[%d]: %w", i, err)
+		}
+		if sh.Services < 1 || sh.Replicas < 1 {
+			return nil, fmt.Errorf("shapes[%d]: services and replicas must be greater than 0", i)
+		}
+
+		out.shapes = append(out.shapes, shape{Shape: sh, cpu: cpu, memory: memory, tolerations: tolerations})
+		out.total += sh.Services
+	}
+
+	return out, nil
+}
+
+// draw returns a random shape, shapes are drawn proportionally to their number of services
+func (m *model) draw(r *rand.Rand) shape {
+	n := r.Intn(m.total)
+	for _, sh := range m.shapes {
+		if n < sh.Services {
+			return sh
+		}
+		n -= sh.Services
+	}
+
+	return m.shapes[len(m.shapes)-1]
+}
+
+// serviceShape returns shape of service with given replicas from its first pod
+func serviceShape(pod *corev1.Pod, replicas int) Shape {
+	cpu, memory := podRequests(pod)
+	f := podFeatures(pod)
+
+	sh := Shape{
+		Replicas:     replicas,
+		CPU:          resource.NewMilliQuantity(int64(math.Round(cpu*1000)), resource.DecimalSI).String(),
+		Memory:       resource.NewQuantity(int64(math.Round(memory/(1<<20)))<<20, resource.BinarySI).String(),
+		AntiAffinity: f[1],
+		Spread:       f[5],
+	}
+
+	for _, t := range pod.Spec.Tolerations {
+		// tolerations of any taint have no key and can not be written as key[=value][:Effect]
+		if t.Key == "" {
+			continue
+		}
+
+		spec := t.Key
+		if t.Operator != corev1.TolerationOpExists && t.Value != "" {
+			spec += "=" + t.Value
+		}
+		if t.Effect != "" {
+			spec += ":" + string(t.Effect)
+		}
+		sh.Tolerations = append(sh.Tolerations, spec)
+	}
+
+	return sh
+}
+
+// podRequests returns cpu cores and memory bytes requested by pod containers
+func podRequests(pod *corev1.Pod) (float64, float64) {
+	cpu, memory := resource.Quantity{}, resource.Quantity{}
+	for _, c := range pod.Spec.Containers {
+		cpu.Add(c.Resources.Requests[corev1.ResourceCPU])
+		memory.Add(c.Resources.Requests[corev1.ResourceMemory])
+	}
+
+	return cpu.AsApproximateFloat64(), memory.AsApproximateFloat64()
+}
+
+// podFeatures returns if pod has pod affinity, pod anti-affinity, node affinity, node selector,
+// tolerations and topology spread constraints
+func podFeatures(pod *corev1.Pod) [6]bool {
+	a := pod.Spec.Affinity
+
+	return [6]bool{
+		a != nil && a.PodAffinity != nil,
+		a != nil && a.PodAntiAffinity != nil,
+		a != nil && a.NodeAffinity != nil,
+		len(pod.Spec.NodeSelector) > 0,
+		len(pod.Spec.Tolerations) > 0,
+		len(pod.Spec.TopologySpreadConstraints) > 0,
+	}
+}
+
+// histogram counts values per bucket, values above the last bound get to +Inf bucket
+func histogram(values []float64, bounds []string) []Bucket {
+	buckets := make([]Bucket, 0, len(bounds)+1)
+	limits := make([]float64, 0, len(bounds))
+	for _, b := range bounds {
+		buckets = append(buckets, Bucket{Le: b})
+		q := resource.MustParse(b)
+		limits = append(limits, q.AsApproximateFloat64())
+	}
+	buckets = append(buckets, Bucket{Le: "+Inf"})
+
+	for _, v := range values {
+		i := sort.SearchFloat64s(limits, v)
+		buckets[i].Count++
+	}
+
+	return buckets
+}
+
+// quantiles returns 10th, 50th and 90th percentiles of byte values rounded to MiB
+func quantiles(values []float64) Quantiles {
+	if len(values) == 0 {
+		return Quantiles{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	q := func(p float64) string {
+		v := stat.Quantile(p, stat.Empirical, sorted, nil)
+		return resource.NewQuantity(int64(math.Round(v/(1<<20)))<<20, resource.BinarySI).String()
+	}
+
+	return Quantiles{P10: q(0.1), P50: q(0.5), P90: q(0.9)}
+}
@@ -0,0 +1,160 @@
+package generate
+
+import (
+	"bufio"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const profileTestPods = `
+{"metadata": {"name": "web-1", "namespace": "prod", "labels": {"service": "web"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "250m", "memory": "512Mi"}}}], "affinity": {"podAntiAffinity": {}}, "tolerations": [{"key": "spot", "operator": "Exists"}]}}
+{"metadata": {"name": "web-2", "namespace": "prod", "labels": {"service": "web"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "250m", "memory": "512Mi"}}}], "affinity": {"podAntiAffinity": {}}, "tolerations": [{"key": "spot", "operator": "Exists"}]}}
+{"metadata": {"name": "web-3", "namespace": "prod", "labels": {"service": "web"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "250m", "memory": "512Mi"}}}], "affinity": {"podAntiAffinity": {}}, "tolerations": [{"key": "spot", "operator": "Exists"}]}}
+{"metadata": {"name": "api-1", "namespace": "prod", "labels": {"service": "api"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "1", "memory": "2Gi"}}}], "topologySpreadConstraints": [{"maxSkew": 1}]}}
+{"metadata": {"name": "cache-1", "namespace": "prod", "labels": {"service": "cache"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "1", "memory": "2Gi"}}}], "topologySpreadConstraints": [{"maxSkew": 1}]}}
+{"metadata": {"name": "db-1", "namespace": "prod", "labels": {"service": "db"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "4", "memory": "16Gi"}}}], "nodeSelector": {"pool": "db"}, "tolerations": [{"key": "dedicated", "operator": "Equal", "value": "db", "effect": "NoSchedule"}]}}
+{"metadata": {"name": "cron-1", "namespace": "prod"}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m", "memory": "128Mi"}}}]}}
+{"metadata": {"name": "cron-2", "namespace": "prod"}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m", "memory": "128Mi"}}}]}}
+{"metadata": {"name": "dns-1", "namespace": "kube-system", "labels": {"service": "dns"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m"}}}]}}
+`
+
+func fitTestModel(t *testing.T) *Model {
+	t.Helper()
+
+	src, err := source.New(bufio.NewReader(strings.NewReader(strings.TrimSpace(profileTestPods))), source.FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Fit(src, _import.PodFilter{ExcludeNamespaces: []string{"kube-system"}})
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	return m
+}
+
+func TestFit(t *testing.T) {
+	m := fitTestModel(t)
+
+	if m.Pods != 8 || m.Services != 6 || m.GroupBy != _import.DefaultGroupBy {
+		t.Errorf("Fit() pods = %d, services = %d, groupBy = %s, want 8, 6, %s", m.Pods, m.Services, m.GroupBy, _import.DefaultGroupBy)
+	}
+
+	wantShapes := []Shape{
+		{Services: 2, Replicas: 1, CPU: "100m", Memory: "128Mi"},
+		{Services: 2, Replicas: 1, CPU: "1", Memory: "2Gi", Spread: true},
+		{Services: 1, Replicas: 3, CPU: "250m", Memory: "512Mi", AntiAffinity: true, Tolerations: []string{"spot"}},
+		{Services: 1, Replicas: 1, CPU: "4", Memory: "16Gi", Tolerations: []string{"dedicated=db:NoSchedule"}},
+	}
+	if !reflect.DeepEqual(m.Shapes, wantShapes) {
+		t.Errorf("Fit() shapes = %+v, want %+v", m.Shapes, wantShapes)
+	}
+
+	counts := func(buckets []Bucket) map[string]int {
+		out := map[string]int{}
+		for _, b := range buckets {
+			if b.Count > 0 {
+				out[b.Le] = b.Count
+			}
+		}
+		return out
+	}
+	if got, want := counts(m.Replicas), map[string]int{"1": 5, "3": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fit() replicas histogram = %v, want %v", got, want)
+	}
+	if got, want := counts(m.CPU), map[string]int{"100m": 2, "250m": 3, "1": 2, "4": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fit() cpu histogram = %v, want %v", got, want)
+	}
+	if got, want := counts(m.Memory), map[string]int{"128Mi": 2, "512Mi": 3, "2Gi": 2, "16Gi": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fit() memory histogram = %v, want %v", got, want)
+	}
+	if m.Memory[len(m.Memory)-1].Le != "+Inf" {
+		t.Errorf("Fit() memory histogram has no +Inf bucket")
+	}
+
+	if m.CPUMemoryCorrelation < 0.99 {
+		t.Errorf("Fit() cpu memory correlation = %v, want ~1", m.CPUMemoryCorrelation)
+	}
+	if m.MemoryPerCPU.P50 != "2Gi" {
+		t.Errorf("Fit() memory per cpu p50 = %s, want 2Gi", m.MemoryPerCPU.P50)
+	}
+
+	wantFeatures := Features{AntiAffinity: 0.375, NodeSelector: 0.125, Tolerations: 0.5, Spread: 0.25}
+	if m.Features != wantFeatures {
+		t.Errorf("Fit() features = %+v, want %+v", m.Features, wantFeatures)
+	}
+}
+
+func TestModelWorkload(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "model.yaml")
+	if err := fitTestModel(t).WriteFile(modelPath); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	w := Workload{Seed: 1, Services: []ServiceGroup{{Name: "synthetic", Count: 600, Model: modelPath}}}
+	pods, err := w.Pods()
+	if err != nil {
+		t.Fatalf("Pods() error = %v", err)
+	}
+
+	shapes := map[string]int{}
+	for _, p := range pods {
+		if p.Name != p.Labels[ServiceLabel]+"-0" {
+			continue
+		}
+		cpu, mem := requests(p)
+		shapes[cpu.String()+"/"+mem.String()]++
+
+		switch cpu.String() {
+		case "250m":
+			if p.Spec.Affinity == nil || p.Spec.Affinity.PodAntiAffinity == nil || len(p.Spec.Tolerations) != 1 {
+				t.Errorf("pod %s of shape with anti-affinity and toleration = %+v", p.Name, p.Spec)
+			}
+		case "1":
+			if len(p.Spec.TopologySpreadConstraints) != 1 || p.Spec.TopologySpreadConstraints[0].TopologyKey != DefaultZoneLabel {
+				t.Errorf("pod %s of shape with spread = %+v", p.Name, p.Spec)
+			}
+		}
+	}
+
+	// shapes are drawn proportionally to services: 2:2:1:1 of 600
+	want := map[string]int{"100m/128Mi": 200, "1/2Gi": 200, "250m/512Mi": 100, "4/16Gi": 100}
+	for shape, n := range want {
+		if got := shapes[shape]; got < n*3/4 || got > n*5/4 {
+			t.Errorf("shape %s drawn for %d services, want about %d", shape, got, n)
+		}
+	}
+
+	if err = (Workload{Services: []ServiceGroup{{Name: "a", Count: 1, Model: modelPath, CPU: Distribution{Value: "1"}}}}).Validate(); err == nil {
+		t.Error("Validate() error = nil, want model and cpu conflict error")
+	}
+}
+
+func TestLoadModelRejectsNewerVersion(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "model.yaml")
+	if err := (&Model{Version: ModelVersion + 1}).WriteFile(modelPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadModel(modelPath); err == nil {
+		t.Error("LoadModel() error = nil, want unsupported version error")
+	}
+}
+
+func TestLoadModelRejectsUnknownField(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "model.yaml")
+	if err := ioutil.WriteFile(modelPath, []byte("version: 1\npods: 10\nshape:\n  - {services: 1, replicas: 3}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadModel(modelPath); err == nil || !strings.Contains(err.Error(), "shape") {
+		t.Errorf("LoadModel() error = %v, want unknown field shape error", err)
+	}
+}
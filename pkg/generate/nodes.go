@@ -0,0 +1,371 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const (
+	// DefaultZoneLabel is a label of node zone
+	DefaultZoneLabel = "topology.kubernetes.io/zone"
+	// DefaultRackLabel is a label of node rack, there is no well-known one
+	DefaultRackLabel = "topology.kubernetes.io/rack"
+
+	labelHostname = "kubernetes.io/hostname"
+)
+
+// Fleet describes a synthetic nodes fleet: every template is cloned count times, clones are spread over zones and racks
+type Fleet struct {
+	// TemplatesFile is a nodes file or directory of manifests templates are cloned from
+	TemplatesFile string `yaml:"templatesFile"`
+	// Templates are node shapes of the fleet
+	Templates []NodeTemplate `yaml:"templates"`
+	// Zones are assigned to clones of every template round-robin, empty - zone label of template is kept
+	Zones []string `yaml:"zones"`
+	// ZoneLabel is a label key of zone, DefaultZoneLabel by default
+	ZoneLabel string `yaml:"zoneLabel"`
+	// RacksPerZone is a number of racks in every zone, clones fill racks of their zone round-robin, 0 - no rack label
+	RacksPerZone int `yaml:"racksPerZone"`
+	// RackLabel is a label key of rack, DefaultRackLabel by default
+	RackLabel string `yaml:"rackLabel"`
+}
+
+// NodeTemplate describes a node shape and number of its clones
+type NodeTemplate struct {
+	// Name is a prefix of clone names, template node name by default, clones are named <name>-<n>
+	Name string `yaml:"name"`
+	// From is a name of node in templates file to clone
+	From string `yaml:"from"`
+	// Node is an inline node manifest to clone if From is empty
+	Node map[string]interface{} `yaml:"node"`
+	// Count is a number of clones
+	Count int `yaml:"count"`
+	// Labels are added to clones overriding template labels
+	Labels map[string]string `yaml:"labels"`
+	// Taints are added to clones: key[=value]:Effect
+	Taints []string `yaml:"taints"`
+	// Allocatable overrides allocatable resources of clones, capacity is raised to allocatable if it is lower
+	Allocatable map[string]string `yaml:"allocatable"`
+}
+
+// LoadFleet reads fleet spec from YAML or JSON file and validates it
+func LoadFleet(filePath string) (*Fleet, error) {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fleet{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(contents))
+	dec.KnownFields(true)
+	if err = dec.Decode(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	if err = f.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return f, nil
+}
+
+// Validate checks templates, taints and quantities, templates file is not read
+func (f Fleet) Validate() error {
+	if len(f.Templates) == 0 {
+		return fmt.Errorf("templates: at least one template is required")
+	}
+	if f.RacksPerZone < 0 {
+		return fmt.Errorf("racksPerZone: must not be negative")
+	}
+
+	names := map[string]bool{}
+	for i, t := range f.Templates {
+		if err := t.validate(f.TemplatesFile); err != nil {
+			return fmt.Errorf("templates[%d]: %w", i, err)
+		}
+
+		name := t.prefix()
+		if name == "" {
+			return fmt.Errorf("templates[%d]: name or node metadata.name is required", i)
+		}
+		if names[name] {
+			return fmt.Errorf("templates[%d]: name %q is not unique, clone names would collide", i, name)
+		}
+		names[name] = true
+	}
+
+	return nil
+}
+
+func (t NodeTemplate) validate(templatesFile string) error {
+	switch {
+	case t.From == "" && t.Node == nil:
+		return fmt.Errorf("from or node is required")
+	case t.From != "" && t.Node != nil:
+		return fmt.Errorf("from and node are mutually exclusive")
+	case t.From != "" && templatesFile == "":
+		return fmt.Errorf("from: templatesFile is required")
+	case t.Count < 0:
+		return fmt.Errorf("count: must not be negative")
+	}
+
+	if _, err := parseTaints(t.Taints); err != nil {
+		return fmt.Errorf("taints: %w", err)
+	}
+
+	if _, err := parseResources(t.Allocatable); err != nil {
+		return fmt.Errorf("allocatable: %w", err)
+	}
+
+	return nil
+}
+
+// prefix returns clone names prefix: template name, from or inline node metadata.name, empty if there is none of them
+func (t NodeTemplate) prefix() string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.From != "":
+		return t.From
+	}
+
+	metadata, _ := t.Node["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+
+	return name
+}
+
+// Nodes generates fleet nodes, templates are read from templates file if any template refers to it
+func (f Fleet) Nodes() ([]corev1.Node, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	var byName map[string]*corev1.Node
+	for _, t := range f.Templates {
+		if t.From != "" {
+			var err error
+			if byName, err = readTemplates(f.TemplatesFile); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	zoneLabel, rackLabel := f.ZoneLabel, f.RackLabel
+	if zoneLabel == "" {
+		zoneLabel = DefaultZoneLabel
+	}
+	if rackLabel == "" {
+		rackLabel = DefaultRackLabel
+	}
+
+	var nodes []corev1.Node
+	for i, t := range f.Templates {
+		template, err := t.template(byName)
+		if err != nil {
+			return nil, fmt.Errorf("templates[%d]: %w", i, err)
+		}
+
+		// validated above
+		taints, _ := parseTaints(t.Taints)
+		allocatable, _ := parseResources(t.Allocatable)
+
+		for n := 0; n < t.Count; n++ {
+			node := clone(template, fmt.Sprintf("%s-%d", t.prefix(), n))
+
+			for k, v := range t.Labels {
+				node.Labels[k] = v
+			}
+			node.Spec.Taints = append(node.Spec.Taints, taints...)
+			for res, q := range allocatable {
+				node.Status.Allocatable[res] = q
+				if c, ok := node.Status.Capacity[res]; !ok || c.Cmp(q) < 0 {
+					node.Status.Capacity[res] = q
+				}
+			}
+
+			zone := node.Labels[zoneLabel]
+			if len(f.Zones) > 0 {
+				zone = f.Zones[n%len(f.Zones)]
+				node.Labels[zoneLabel] = zone
+			}
+			if f.RacksPerZone > 0 {
+				rack := n / max(len(f.Zones), 1) % f.RacksPerZone
+				node.Labels[rackLabel] = rackName(zone, rack)
+			}
+
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes, nil
+}
+
+// Source generates fleet nodes and returns them as an import source
+func (f Fleet) Source() (source.Source, error) {
+	nodes, err := f.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([][]byte, 0, len(nodes))
+	for i := range nodes {
+		b, err := json.Marshal(&nodes[i])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, b)
+	}
+
+	return source.NewMemorySource(items), nil
+}
+
+// template returns template node from templates file or decoded inline manifest
+func (t NodeTemplate) template(byName map[string]*corev1.Node) (*corev1.Node, error) {
+	if t.From != "" {
+		node, ok := byName[t.From]
+		if !ok {
+			return nil, fmt.Errorf("from: node %q is not found in templates file", t.From)
+		}
+		return node, nil
+	}
+
+	// inline manifest is YAML decoded, json tags of api types are applied by JSON round trip
+	b, err := json.Marshal(t.Node)
+	if err != nil {
+		return nil, fmt.Errorf("node: %w", err)
+	}
+
+	node := &corev1.Node{}
+	if err = json.Unmarshal(b, node); err != nil {
+		return nil, fmt.Errorf("node: %w", err)
+	}
+
+	return node, nil
+}
+
+func readTemplates(filePath string) (map[string]*corev1.Node, error) {
+	src, err := source.Open(filePath, source.FormatAuto)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	byName := map[string]*corev1.Node{}
+	for {
+		b, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		node := &corev1.Node{}
+		if err = json.Unmarshal(b, node); err != nil {
+			return nil, fmt.Errorf("%s: node #%d: %w", filePath, len(byName), err)
+		}
+		byName[node.Name] = node
+	}
+
+	return byName, nil
+}
+
+// clone returns a copy of template node as a new node with given name, identity of template is dropped
+func clone(template *corev1.Node, name string) corev1.Node {
+	node := *template.DeepCopy()
+
+	node.TypeMeta.Kind = "Node"
+	node.TypeMeta.APIVersion = "v1"
+	node.Name = name
+	node.UID = ""
+	node.ResourceVersion = ""
+	node.CreationTimestamp.Reset()
+	node.ManagedFields = nil
+	node.Spec.ProviderID = ""
+	node.Spec.PodCIDR = ""
+	node.Spec.PodCIDRs = nil
+	node.Status.Addresses = nil
+
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	node.Labels[labelHostname] = name
+	if node.Status.Allocatable == nil {
+		node.Status.Allocatable = corev1.ResourceList{}
+	}
+	if node.Status.Capacity == nil {
+		node.Status.Capacity = corev1.ResourceList{}
+	}
+
+	return node
+}
+
+func rackName(zone string, rack int) string {
+	if zone == "" {
+		return fmt.Sprintf("rack-%d", rack)
+	}
+
+	return fmt.Sprintf("%s-rack-%d", zone, rack)
+}
+
+// parseTaints parses key[=value]:Effect taint specs
+func parseTaints(specs []string) ([]corev1.Taint, error) {
+	out := make([]corev1.Taint, 0, len(specs))
+	for _, spec := range specs {
+		i := strings.LastIndexByte(spec, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("taint %q has no effect, want key[=value]:Effect", spec)
+		}
+
+		t := corev1.Taint{Effect: corev1.TaintEffect(spec[i+1:])}
+		t.Key = spec[:i]
+		if j := strings.IndexByte(t.Key, '='); j >= 0 {
+			t.Key, t.Value = t.Key[:j], t.Key[j+1:]
+		}
+
+		switch {
+		case t.Key == "":
+			return nil, fmt.Errorf("taint %q has no key", spec)
+		case t.Effect != corev1.TaintEffectNoSchedule && t.Effect != corev1.TaintEffectPreferNoSchedule && t.Effect != corev1.TaintEffectNoExecute:
+			return nil, fmt.Errorf("taint %q has unknown effect %q", spec, t.Effect)
+		}
+
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+func parseResources(resources map[string]string) (corev1.ResourceList, error) {
+	out := corev1.ResourceList{}
+	for res, v := range resources {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", res, err)
+		}
+		out[corev1.ResourceName(res)] = q
+	}
+
+	return out, nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,176 @@
+package generate
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const testTemplates = `{"items": [
+	{"metadata": {"name": "worker-1", "uid": "1", "labels": {"kubernetes.io/hostname": "worker-1", "pool": "web", "topology.kubernetes.io/zone": "a"}},
+	 "spec": {"providerID": "aws:///a/i-1"},
+	 "status": {"capacity": {"cpu": "16", "memory": "64Gi"}, "allocatable": {"cpu": "15", "memory": "60Gi"}, "addresses": [{"type": "InternalIP", "address": "10.0.0.1"}]}},
+	{"metadata": {"name": "worker-2", "labels": {"pool": "db"}}, "status": {"allocatable": {"cpu": "32"}}}
+]}`
+
+func writeTemplates(t *testing.T) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "nodes.json")
+	if err := ioutil.WriteFile(filePath, []byte(testTemplates), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filePath
+}
+
+func TestFleetNodes(t *testing.T) {
+	fleet := Fleet{
+		TemplatesFile: writeTemplates(t),
+		Templates: []NodeTemplate{
+			{
+				Name:        "web",
+				From:        "worker-1",
+				Count:       4,
+				Labels:      map[string]string{"pool": "web-synthetic"},
+				Taints:      []string{"dedicated=web:NoSchedule"},
+				Allocatable: map[string]string{"cpu": "31", "pods": "110"},
+			},
+			{
+				Count: 2,
+				Node: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "gpu"},
+					"status":   map[string]interface{}{"allocatable": map[string]interface{}{"cpu": "8", "nvidia.com/gpu": "1"}},
+				},
+			},
+		},
+		Zones:        []string{"z1", "z2"},
+		RacksPerZone: 2,
+	}
+
+	nodes, err := fleet.Nodes()
+	if err != nil {
+		t.Fatalf("Nodes() error = %v", err)
+	}
+
+	names := []string{}
+	racks := []string{}
+	for _, n := range nodes {
+		names = append(names, n.Name)
+		racks = append(racks, n.Labels[DefaultRackLabel])
+	}
+	if want := []string{"web-0", "web-1", "web-2", "web-3", "gpu-0", "gpu-1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Nodes() names = %v, want %v", names, want)
+	}
+	if want := []string{"z1-rack-0", "z2-rack-0", "z1-rack-1", "z2-rack-1", "z1-rack-0", "z2-rack-0"}; !reflect.DeepEqual(racks, want) {
+		t.Errorf("Nodes() racks = %v, want %v", racks, want)
+	}
+
+	web := nodes[1]
+	if web.UID != "" || web.Spec.ProviderID != "" || len(web.Status.Addresses) != 0 {
+		t.Errorf("clone keeps template identity: %+v", web)
+	}
+	wantLabels := map[string]string{
+		"kubernetes.io/hostname": "web-1",
+		"pool":                   "web-synthetic",
+		DefaultZoneLabel:         "z2",
+		DefaultRackLabel:         "z2-rack-0",
+	}
+	if !reflect.DeepEqual(web.Labels, wantLabels) {
+		t.Errorf("clone labels = %v, want %v", web.Labels, wantLabels)
+	}
+	if want := []corev1.Taint{{Key: "dedicated", Value: "web", Effect: corev1.TaintEffectNoSchedule}}; !reflect.DeepEqual(web.Spec.Taints, want) {
+		t.Errorf("clone taints = %v, want %v", web.Spec.Taints, want)
+	}
+
+	for res, want := range map[corev1.ResourceName]string{"cpu": "31", "memory": "60Gi", "pods": "110"} {
+		if got := web.Status.Allocatable[res]; got.Cmp(resource.MustParse(want)) != 0 {
+			t.Errorf("clone allocatable %s = %s, want %s", res, got.String(), want)
+		}
+	}
+	if got := web.Status.Capacity[corev1.ResourceCPU]; got.Cmp(resource.MustParse("31")) != 0 {
+		t.Errorf("clone capacity cpu = %s, want raised to 31", got.String())
+	}
+	if got := web.Status.Capacity[corev1.ResourceMemory]; got.Cmp(resource.MustParse("64Gi")) != 0 {
+		t.Errorf("clone capacity memory = %s, want 64Gi", got.String())
+	}
+
+	if got := nodes[4].Status.Allocatable["nvidia.com/gpu"]; got.Value() != 1 {
+		t.Errorf("inline clone gpu = %s, want 1", got.String())
+	}
+}
+
+func TestFleetSource(t *testing.T) {
+	fleet := Fleet{
+		TemplatesFile: writeTemplates(t),
+		Templates:     []NodeTemplate{{From: "worker-2", Count: 3}},
+	}
+
+	src, err := fleet.Source()
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+
+	count := 0
+	for {
+		b, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		node := map[string]interface{}{}
+		if err = json.Unmarshal(b, &node); err != nil {
+			t.Fatalf("Source() item is invalid json: %v: %s", err, b)
+		}
+		if node["kind"] != "Node" {
+			t.Errorf("Source() item kind = %v, want Node", node["kind"])
+		}
+		count++
+	}
+
+	if count != 3 {
+		t.Errorf("Source() returned %d nodes, want 3", count)
+	}
+}
+
+func TestFleetValidate(t *testing.T) {
+	inline := map[string]interface{}{"metadata": map[string]interface{}{"name": "n"}}
+
+	tests := []struct {
+		name  string
+		fleet Fleet
+	}{
+		{"no templates", Fleet{}},
+		{"no source", Fleet{Templates: []NodeTemplate{{Count: 1}}}},
+		{"no templates file", Fleet{Templates: []NodeTemplate{{From: "worker-1"}}}},
+		{"from and node", Fleet{TemplatesFile: "nodes.json", Templates: []NodeTemplate{{From: "worker-1", Node: inline}}}},
+		{"taint effect", Fleet{Templates: []NodeTemplate{{Node: inline, Taints: []string{"dedicated=web"}}}}},
+		{"quantity", Fleet{Templates: []NodeTemplate{{Node: inline, Allocatable: map[string]string{"cpu": "lots"}}}}},
+		{"duplicate names", Fleet{Templates: []NodeTemplate{{Name: "a", Node: inline}, {Name: "a", Node: inline}}}},
+		{"duplicate node names", Fleet{Templates: []NodeTemplate{{Node: inline, Count: 1}, {Node: inline, Count: 2}}}},
+		{"no node name", Fleet{Templates: []NodeTemplate{{Node: map[string]interface{}{"metadata": map[string]interface{}{}}}}}},
+		{"racks", Fleet{Templates: []NodeTemplate{{Node: inline}}, RacksPerZone: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fleet.Validate(); err == nil {
+				t.Error("Validate() error = nil, want error")
+			}
+		})
+	}
+
+	_, err := Fleet{TemplatesFile: writeTemplates(t), Templates: []NodeTemplate{{From: "missing", Count: 1}}}.Nodes()
+	if err == nil {
+		t.Error("Nodes() error = nil, want missing template error")
+	}
+}
@@ -0,0 +1,524 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const (
+	// ServiceLabel is a label of generated pods with their service name, importer caps pods per its value by default
+	ServiceLabel = "service"
+	// GroupLabel is a label of generated pods with their service group name, pod affinity selects groups by it
+	GroupLabel = "group"
+	// Image is an image of generated containers
+	Image = "generated"
+)
+
+// Workload describes synthetic pods: groups of services with replicas whose requests follow distributions
+type Workload struct {
+	// Seed makes workload reproducible, same seed gives same pods
+	Seed int64 `yaml:"seed"`
+	// Namespace of pods, default by default
+	Namespace string `yaml:"namespace"`
+	// Services are service groups of the workload
+	Services []ServiceGroup `yaml:"services"`
+}
+
+// ServiceGroup describes count services of one kind, every service draws its pod requests once,
+// so all replicas of a service are alike
+type ServiceGroup struct {
+	// Name is a group name, services are named <name>-<i> and their pods <service>-<j>
+	Name string `yaml:"name"`
+	// Count is a number of services in the group
+	Count int `yaml:"count"`
+	// Model is a workload model file fitted by profile-workload, services draw replicas, requests and constraints
+	// from model shapes instead of distributions, AntiAffinity and Spread set constraints of shapes having them
+	Model string `yaml:"model"`
+	// Replicas is a distribution of service replicas, rounded and at least 1, 1 by default
+	Replicas Distribution `yaml:"replicas"`
+	// CPU and Memory are distributions of pod requests, cpu is rounded to millicores and memory to MiB
+	CPU    Distribution `yaml:"cpu"`
+	Memory Distribution `yaml:"memory"`
+	// PriorityClassName of pods
+	PriorityClassName string `yaml:"priorityClassName"`
+	// Labels are added to pods
+	Labels map[string]string `yaml:"labels"`
+	// NodeSelector of pods
+	NodeSelector map[string]string `yaml:"nodeSelector"`
+	// Tolerations of pods: key[=value][:Effect], key without value is tolerated with Exists operator
+	Tolerations []string `yaml:"tolerations"`
+	// AntiAffinity keeps replicas of a service apart
+	AntiAffinity *AntiAffinity `yaml:"antiAffinity"`
+	// Affinity places pods next to pods of another group
+	Affinity *Affinity `yaml:"affinity"`
+	// Spread are topology spread constraints of service replicas
+	Spread []Spread `yaml:"spread"`
+}
+
+// AntiAffinity is a pod anti-affinity of service replicas to each other
+type AntiAffinity struct {
+	// TopologyKey is kubernetes.io/hostname by default
+	TopologyKey string `yaml:"topologyKey"`
+	// Required makes anti-affinity a filter, otherwise it is preferred with Weight
+	Required bool  `yaml:"required"`
+	Weight   int32 `yaml:"weight"`
+}
+
+// Affinity is a pod affinity to pods of a service group
+type Affinity struct {
+	// Group is a name of service group
+	Group string `yaml:"group"`
+	// TopologyKey is topology.kubernetes.io/zone by default
+	TopologyKey string `yaml:"topologyKey"`
+	// Required makes affinity a filter, otherwise it is preferred with Weight
+	Required bool  `yaml:"required"`
+	Weight   int32 `yaml:"weight"`
+}
+
+// Spread is a topology spread constraint of service replicas
+type Spread struct {
+	TopologyKey string `yaml:"topologyKey"`
+	// MaxSkew is 1 by default
+	MaxSkew int32 `yaml:"maxSkew"`
+	// WhenUnsatisfiable is DoNotSchedule or ScheduleAnyway, DoNotSchedule by default
+	WhenUnsatisfiable string `yaml:"whenUnsatisfiable"`
+}
+
+// serviceGroup is a compiled ServiceGroup
+type serviceGroup struct {
+	ServiceGroup
+	replicas, cpu, memory sampler
+	model                 *model
+	tolerations           []corev1.Toleration
+}
+
+// service is a drawn service of a group
+type service struct {
+	replicas     int
+	cpu, memory  float64
+	antiAffinity *AntiAffinity
+	spread       []Spread
+	tolerations  []corev1.Toleration
+}
+
+// LoadWorkload reads workload spec from YAML or JSON file and validates it
+func LoadWorkload(filePath string) (*Workload, error) {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workload{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(contents))
+	dec.KnownFields(true)
+	if err = dec.Decode(w); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	if err = w.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return w, nil
+}
+
+// Validate checks groups and distributions, files of empirical distributions are not read
+func (w Workload) Validate() error {
+	_, err := w.compile(nil)
+	return err
+}
+
+func (w Workload) compile(files *fileCache) ([]serviceGroup, error) {
+	if len(w.Services) == 0 {
+		return nil, fmt.Errorf("services: at least one service group is required")
+	}
+
+	names := map[string]bool{}
+	out := make([]serviceGroup, 0, len(w.Services))
+	for i, g := range w.Services {
+		if names[g.Name] {
+			return nil, fmt.Errorf("services[%d]: name %q is not unique", i, g.Name)
+		}
+		names[g.Name] = true
+
+		sg, err := g.compile(files)
+		if err != nil {
+			return nil, fmt.Errorf("services[%d]: %w", i, err)
+		}
+		out = append(out, sg)
+	}
+
+	for i, g := range w.Services {
+		if g.Affinity != nil && !names[g.Affinity.Group] {
+			return nil, fmt.Errorf("services[%d]: affinity: unknown group %q", i, g.Affinity.Group)
+		}
+	}
+
+	return out, nil
+}
+
+func (g ServiceGroup) compile(files *fileCache) (serviceGroup, error) {
+	sg := serviceGroup{ServiceGroup: g}
+
+	switch {
+	case g.Name == "":
+		return sg, fmt.Errorf("name is required")
+	case g.Count < 1:
+		return sg, fmt.Errorf("count: must be greater than 0")
+	}
+
+	var err error
+	if g.Model != "" {
+		if !g.Replicas.isZero() || !g.CPU.isZero() || !g.Memory.isZero() {
+			return sg, fmt.Errorf("model: replicas, cpu and memory are drawn from model and must not be set")
+		}
+		if files != nil {
+			if sg.model, err = files.model(g.Model); err != nil {
+				return sg, fmt.Errorf("model: %w", err)
+			}
+		}
+	} else {
+		replicas := g.Replicas
+		if replicas.isZero() {
+			replicas.Value = "1"
+		}
+
+		if sg.replicas, err = replicas.compile("", files); err != nil {
+			return sg, fmt.Errorf("replicas: %w", err)
+		}
+		if sg.cpu, err = g.CPU.compile(string(corev1.ResourceCPU), files); err != nil {
+			return sg, fmt.Errorf("cpu: %w", err)
+		}
+		if sg.memory, err = g.Memory.compile(string(corev1.ResourceMemory), files); err != nil {
+			return sg, fmt.Errorf("memory: %w", err)
+		}
+	}
+
+	if sg.tolerations, err = parseTolerations(g.Tolerations); err != nil {
+		return sg, fmt.Errorf("tolerations: %w", err)
+	}
+
+	for i, s := range g.Spread {
+		switch {
+		case s.TopologyKey == "":
+			return sg, fmt.Errorf("spread[%d]: topologyKey is required", i)
+		case s.MaxSkew < 0:
+			return sg, fmt.Errorf("spread[%d]: maxSkew must not be negative", i)
+		case s.WhenUnsatisfiable != "" && s.WhenUnsatisfiable != string(corev1.DoNotSchedule) && s.WhenUnsatisfiable != string(corev1.ScheduleAnyway):
+			return sg, fmt.Errorf("spread[%d]: unknown whenUnsatisfiable %q", i, s.WhenUnsatisfiable)
+		}
+	}
+
+	return sg, nil
+}
+
+// Pods generates workload pods, services of every group are generated in order
+func (w Workload) Pods() ([]corev1.Pod, error) {
+	groups, err := w.compile(newFileCache())
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := w.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	r := rand.New(rand.NewSource(w.Seed))
+
+	var pods []corev1.Pod
+	for _, g := range groups {
+		for i := 0; i < g.Count; i++ {
+			name := fmt.Sprintf("%s-%d", g.Name, i)
+
+			s := g.draw(r)
+			template := g.pod(name, namespace, s)
+			for j := 0; j < s.replicas; j++ {
+				pod := *template.DeepCopy()
+				pod.Name = fmt.Sprintf("%s-%d", name, j)
+				pods = append(pods, pod)
+			}
+		}
+	}
+
+	return pods, nil
+}
+
+// Source generates workload pods and returns them as an import source
+func (w Workload) Source() (source.Source, error) {
+	pods, err := w.Pods()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([][]byte, 0, len(pods))
+	for i := range pods {
+		b, err := json.Marshal(&pods[i])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, b)
+	}
+
+	return source.NewMemorySource(items), nil
+}
+
+// draw draws service replicas and requests from distributions or a shape from model
+func (g serviceGroup) draw(r *rand.Rand) service {
+	if g.model == nil {
+		s := service{
+			replicas:     int(math.Round(g.replicas(r))),
+			cpu:          g.cpu(r),
+			memory:       g.memory(r),
+			antiAffinity: g.AntiAffinity,
+			spread:       g.Spread,
+			tolerations:  g.tolerations,
+		}
+		if s.replicas < 1 {
+			s.replicas = 1
+		}
+		return s
+	}
+
+	sh := g.model.draw(r)
+	s := service{
+		replicas:    sh.Replicas,
+		cpu:         sh.cpu,
+		memory:      sh.memory,
+		tolerations: append(append([]corev1.Toleration(nil), g.tolerations...), sh.tolerations...),
+	}
+
+	if sh.AntiAffinity {
+		s.antiAffinity = g.AntiAffinity
+		if s.antiAffinity == nil {
+			s.antiAffinity = &AntiAffinity{}
+		}
+	}
+	if sh.Spread {
+		s.spread = g.Spread
+		if len(s.spread) == 0 {
+			s.spread = []Spread{{TopologyKey: DefaultZoneLabel, WhenUnsatisfiable: string(corev1.ScheduleAnyway)}}
+		}
+	}
+
+	return s
+}
+
+// pod returns pod template of drawn service
+func (g serviceGroup) pod(name, namespace string, s service) *corev1.Pod {
+	// cpu is rounded to millicores and memory to MiB like people write requests
+	cpu := resource.NewMilliQuantity(int64(math.Max(1, math.Round(s.cpu*1000))), resource.DecimalSI)
+	memory := resource.NewQuantity(int64(math.Max(1, math.Round(s.memory/(1<<20))))<<20, resource.BinarySI)
+
+	labels := map[string]string{}
+	for k, v := range g.Labels {
+		labels[k] = v
+	}
+	labels[ServiceLabel] = name
+	labels[GroupLabel] = g.Name
+
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "main",
+				Image: Image,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: *cpu, corev1.ResourceMemory: *memory},
+				},
+			}},
+			PriorityClassName: g.PriorityClassName,
+			NodeSelector:      g.NodeSelector,
+			Tolerations:       s.tolerations,
+		},
+	}
+
+	serviceSelector := &metav1.LabelSelector{MatchLabels: map[string]string{ServiceLabel: name}}
+
+	if a := s.antiAffinity; a != nil {
+		term := corev1.PodAffinityTerm{LabelSelector: serviceSelector, TopologyKey: orDefault(a.TopologyKey, corev1.LabelHostname)}
+
+		anti := &corev1.PodAntiAffinity{}
+		if a.Required {
+			anti.RequiredDuringSchedulingIgnoredDuringExecution = []corev1.PodAffinityTerm{term}
+		} else {
+			anti.PreferredDuringSchedulingIgnoredDuringExecution = []corev1.WeightedPodAffinityTerm{{Weight: weight(a.Weight), PodAffinityTerm: term}}
+		}
+		pod.Spec.Affinity = &corev1.Affinity{PodAntiAffinity: anti}
+	}
+
+	if a := g.Affinity; a != nil {
+		term := corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{GroupLabel: a.Group}},
+			TopologyKey:   orDefault(a.TopologyKey, DefaultZoneLabel),
+		}
+
+		affinity := &corev1.PodAffinity{}
+		if a.Required {
+			affinity.RequiredDuringSchedulingIgnoredDuringExecution = []corev1.PodAffinityTerm{term}
+		} else {
+			affinity.PreferredDuringSchedulingIgnoredDuringExecution = []corev1.WeightedPodAffinityTerm{{Weight: weight(a.Weight), PodAffinityTerm: term}}
+		}
+		if pod.Spec.Affinity == nil {
+			pod.Spec.Affinity = &corev1.Affinity{}
+		}
+		pod.Spec.Affinity.PodAffinity = affinity
+	}
+
+	for _, spread := range s.spread {
+		maxSkew := spread.MaxSkew
+		if maxSkew == 0 {
+			maxSkew = 1
+		}
+
+		pod.Spec.TopologySpreadConstraints = append(pod.Spec.TopologySpreadConstraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           maxSkew,
+			TopologyKey:       spread.TopologyKey,
+			WhenUnsatisfiable: corev1.UnsatisfiableConstraintAction(orDefault(spread.WhenUnsatisfiable, string(corev1.DoNotSchedule))),
+			LabelSelector:     serviceSelector,
+		})
+	}
+
+	return pod
+}
+
+// parseTolerations parses key[=value][:Effect] toleration specs
+func parseTolerations(specs []string) ([]corev1.Toleration, error) {
+	var out []corev1.Toleration
+	for _, spec := range specs {
+		t := corev1.Toleration{Operator: corev1.TolerationOpExists}
+
+		rest := spec
+		if i := strings.LastIndexByte(rest, ':'); i >= 0 {
+			rest, t.Effect = rest[:i], corev1.TaintEffect(rest[i+1:])
+		}
+		if i := strings.IndexByte(rest, '='); i >= 0 {
+			rest, t.Value, t.Operator = rest[:i], rest[i+1:], corev1.TolerationOpEqual
+		}
+		t.Key = rest
+
+		if t.Key == "" {
+			return nil, fmt.Errorf("toleration %q has no key", spec)
+		}
+
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+// fileCache reads pod requests samples and models of every file once
+type fileCache struct {
+	requests map[string]map[string][]float64
+	models   map[string]*model
+}
+
+func newFileCache() *fileCache {
+	return &fileCache{
+		requests: map[string]map[string][]float64{},
+		models:   map[string]*model{},
+	}
+}
+
+func (c *fileCache) samples(file, res string) ([]float64, error) {
+	if _, ok := c.requests[file]; !ok {
+		byRes, err := readRequests(file)
+		if err != nil {
+			return nil, err
+		}
+		c.requests[file] = byRes
+	}
+
+	return c.requests[file][res], nil
+}
+
+func (c *fileCache) model(file string) (*model, error) {
+	if m, ok := c.models[file]; ok {
+		return m, nil
+	}
+
+	spec, err := LoadModel(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := spec.compile()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	c.models[file] = m
+
+	return m, nil
+}
+
+// readRequests returns cpu and memory requests of every pod in file, sum of containers requests, pods without requests are skipped
+func readRequests(file string) (map[string][]float64, error) {
+	src, err := source.Open(file, source.FormatAuto)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	out := map[string][]float64{}
+	for n := 0; ; n++ {
+		b, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pod := &corev1.Pod{}
+		if err = json.Unmarshal(b, pod); err != nil {
+			return nil, fmt.Errorf("%s: pod #%d: %w", file, n, err)
+		}
+
+		for _, res := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			sum := resource.Quantity{}
+			for _, c := range pod.Spec.Containers {
+				sum.Add(c.Resources.Requests[res])
+			}
+			if !sum.IsZero() {
+				out[string(res)] = append(out[string(res)], sum.AsApproximateFloat64())
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+
+	return v
+}
+
+// weight returns weight of preferred term, 100 by default
+func weight(w int32) int32 {
+	if w == 0 {
+		return 100
+	}
+
+	return w
+}
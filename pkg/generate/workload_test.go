@@ -0,0 +1,234 @@
+package generate
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testWorkload() Workload {
+	return Workload{
+		Seed: 42,
+		Services: []ServiceGroup{
+			{
+				Name:         "web",
+				Count:        3,
+				Replicas:     Distribution{Type: Uniform, Min: "2", Max: "6"},
+				CPU:          Distribution{Type: LogNormal, Median: "250m", Sigma: 0.5, Max: "1"},
+				Memory:       Distribution{Type: Empirical, Values: []string{"256Mi", "512Mi"}},
+				Tolerations:  []string{"dedicated=web:NoSchedule", "spot"},
+				AntiAffinity: &AntiAffinity{},
+				Spread:       []Spread{{TopologyKey: "topology.kubernetes.io/zone"}},
+			},
+			{
+				Name:     "db",
+				Count:    1,
+				Replicas: Distribution{Value: "2"},
+				CPU:      Distribution{Value: "4"},
+				Memory:   Distribution{Value: "16Gi"},
+				Affinity: &Affinity{Group: "web", Required: true},
+			},
+		},
+	}
+}
+
+func requests(pod corev1.Pod) (resource.Quantity, resource.Quantity) {
+	r := pod.Spec.Containers[0].Resources.Requests
+	return r[corev1.ResourceCPU], r[corev1.ResourceMemory]
+}
+
+func TestWorkloadPods(t *testing.T) {
+	pods, err := testWorkload().Pods()
+	if err != nil {
+		t.Fatalf("Pods() error = %v", err)
+	}
+
+	perService := map[string][]corev1.Pod{}
+	for _, p := range pods {
+		perService[p.Labels[ServiceLabel]] = append(perService[p.Labels[ServiceLabel]], p)
+	}
+
+	if len(perService) != 4 {
+		t.Fatalf("Pods() generated %d services, want 4", len(perService))
+	}
+
+	for service, replicas := range perService {
+		if len(replicas) < 2 || len(replicas) > 6 {
+			t.Errorf("service %s has %d replicas, want 2..6", service, len(replicas))
+		}
+
+		cpu, mem := requests(replicas[0])
+		for _, p := range replicas {
+			if c, m := requests(p); c.Cmp(cpu) != 0 || m.Cmp(mem) != 0 {
+				t.Errorf("service %s replicas have different requests", service)
+			}
+		}
+		if cpu.Cmp(resource.MustParse("1")) > 0 && service != "db-0" {
+			t.Errorf("service %s cpu = %s, want clamped to 1", service, cpu.String())
+		}
+	}
+
+	db := perService["db-0"]
+	if len(db) != 2 || db[1].Name != "db-0-1" || db[0].Namespace != "default" {
+		t.Fatalf("db pods = %v", db)
+	}
+	if cpu, mem := requests(db[0]); cpu.String() != "4" || mem.String() != "16Gi" {
+		t.Errorf("db requests = %s, %s, want 4, 16Gi", cpu.String(), mem.String())
+	}
+	term := db[0].Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0]
+	if term.LabelSelector.MatchLabels[GroupLabel] != "web" || term.TopologyKey != DefaultZoneLabel {
+		t.Errorf("db affinity = %+v, want to web group in zone", term)
+	}
+
+	web := perService["web-1"][0]
+	anti := web.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0]
+	if anti.Weight != 100 || anti.PodAffinityTerm.LabelSelector.MatchLabels[ServiceLabel] != "web-1" || anti.PodAffinityTerm.TopologyKey != corev1.LabelHostname {
+		t.Errorf("web anti-affinity = %+v", anti)
+	}
+	spread := web.Spec.TopologySpreadConstraints[0]
+	if spread.MaxSkew != 1 || spread.WhenUnsatisfiable != corev1.DoNotSchedule || spread.LabelSelector.MatchLabels[ServiceLabel] != "web-1" {
+		t.Errorf("web spread = %+v", spread)
+	}
+	wantTolerations := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "web", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "spot", Operator: corev1.TolerationOpExists},
+	}
+	if !reflect.DeepEqual(web.Spec.Tolerations, wantTolerations) {
+		t.Errorf("web tolerations = %v, want %v", web.Spec.Tolerations, wantTolerations)
+	}
+}
+
+func TestWorkloadSeed(t *testing.T) {
+	w := testWorkload()
+
+	a, err := w.Pods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := w.Pods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("Pods() with same seed generated different pods")
+	}
+
+	w.Seed++
+	c, err := w.Pods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(a, c) {
+		t.Error("Pods() with different seeds generated same pods")
+	}
+}
+
+func TestDistributions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	uniform, err := Distribution{Type: Uniform, Min: "100m", Max: "300m"}.compile("cpu", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lognormal, err := Distribution{Type: LogNormal, Median: "1Gi", Sigma: 1}.compile("memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var uniformSum float64
+	logs := []float64{}
+	for i := 0; i < 10000; i++ {
+		v := uniform(r)
+		if v < 0.1 || v > 0.3 {
+			t.Fatalf("uniform value %v is out of range", v)
+		}
+		uniformSum += v
+		logs = append(logs, math.Log(lognormal(r)/(1<<30)))
+	}
+
+	if mean := uniformSum / 10000; math.Abs(mean-0.2) > 0.005 {
+		t.Errorf("uniform mean = %v, want 0.2", mean)
+	}
+
+	var mean, variance float64
+	for _, l := range logs {
+		mean += l / float64(len(logs))
+	}
+	for _, l := range logs {
+		variance += (l - mean) * (l - mean) / float64(len(logs))
+	}
+	if math.Abs(mean) > 0.05 || math.Abs(math.Sqrt(variance)-1) > 0.05 {
+		t.Errorf("lognormal log mean = %v, sigma = %v, want 0, 1", mean, math.Sqrt(variance))
+	}
+}
+
+func TestEmpiricalFromFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "pods.json")
+	err := ioutil.WriteFile(filePath, []byte(`{"items": [
+		{"metadata": {"name": "a"}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m", "memory": "1Gi"}}}, {"resources": {"requests": {"cpu": "50m"}}}]}},
+		{"metadata": {"name": "b"}, "spec": {"containers": [{"resources": {"requests": {"memory": "2Gi"}}}]}}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := Workload{Services: []ServiceGroup{{
+		Name:   "copy",
+		Count:  20,
+		CPU:    Distribution{Type: Empirical, File: filePath},
+		Memory: Distribution{Type: Empirical, File: filePath},
+	}}}
+
+	pods, err := w.Pods()
+	if err != nil {
+		t.Fatalf("Pods() error = %v", err)
+	}
+
+	for _, p := range pods {
+		cpu, mem := requests(p)
+		if cpu.String() != "150m" {
+			t.Errorf("pod %s cpu = %s, want 150m, the only cpu sample", p.Name, cpu.String())
+		}
+		if mem.String() != "1Gi" && mem.String() != "2Gi" {
+			t.Errorf("pod %s memory = %s, want 1Gi or 2Gi", p.Name, mem.String())
+		}
+	}
+}
+
+func TestWorkloadValidate(t *testing.T) {
+	cpu, mem := Distribution{Value: "1"}, Distribution{Value: "1Gi"}
+
+	tests := []struct {
+		name  string
+		group ServiceGroup
+	}{
+		{"no name", ServiceGroup{Count: 1, CPU: cpu, Memory: mem}},
+		{"no count", ServiceGroup{Name: "a", CPU: cpu, Memory: mem}},
+		{"no cpu", ServiceGroup{Name: "a", Count: 1, Memory: mem}},
+		{"type", ServiceGroup{Name: "a", Count: 1, CPU: Distribution{Type: "normal"}, Memory: mem}},
+		{"uniform bounds", ServiceGroup{Name: "a", Count: 1, CPU: Distribution{Type: Uniform, Min: "2", Max: "1"}, Memory: mem}},
+		{"lognormal median", ServiceGroup{Name: "a", Count: 1, CPU: Distribution{Type: LogNormal, Median: "0"}, Memory: mem}},
+		{"empirical", ServiceGroup{Name: "a", Count: 1, CPU: Distribution{Type: Empirical}, Memory: mem}},
+		{"replicas file", ServiceGroup{Name: "a", Count: 1, CPU: cpu, Memory: mem, Replicas: Distribution{Type: Empirical, File: "pods.json"}}},
+		{"affinity group", ServiceGroup{Name: "a", Count: 1, CPU: cpu, Memory: mem, Affinity: &Affinity{Group: "b"}}},
+		{"spread", ServiceGroup{Name: "a", Count: 1, CPU: cpu, Memory: mem, Spread: []Spread{{TopologyKey: "zone", WhenUnsatisfiable: "Never"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Workload{Services: []ServiceGroup{tt.group}}).Validate(); err == nil {
+				t.Error("Validate() error = nil, want error")
+			}
+		})
+	}
+
+	if err := testWorkload().Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package _import
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,18 +10,19 @@ import (
 )
 
 // ImportConfig imports scheduler configuration from json file to kubernetes-scheduler-simulator
-func ImportConfig(c *client.HTTPClient, filePath string) error {
+func ImportConfig(ctx context.Context, c client.SimulatorClient, filePath string) error {
 	cfg, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.ApplyConfig(cfg)
+	resp, err := c.ApplyConfig(ctx, cfg)
 	if err != nil {
 		return err
 	}
 	b, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode >= http.StatusMultipleChoices {
+		_ = resp.Body.Close()
 		return fmt.Errorf("got scheduler response status: %d: %s", resp.StatusCode, b)
 	}
 
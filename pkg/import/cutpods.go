@@ -2,35 +2,48 @@ package _import
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
 
-	insaneJSON "github.com/vitkovskii/insane-json"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
 )
 
-// CutPods cuts pods from file up to limit and writes rest pods to file with suffix
-func CutPods(filePath string, limit int) error {
-	contents, err := ioutil.ReadFile(filePath)
+// CutPods cuts pods from file up to limit and writes them to file with limit suffix, file is read as a stream
+func CutPods(filePath string, limit int) (err error) {
+	items, err := source.Open(filePath, source.FormatAuto)
 	if err != nil {
 		return err
 	}
+	defer items.Close()
 
-	root, err := insaneJSON.DecodeBytes(contents)
+	out, err := os.Create(fmt.Sprintf("%s-%d", filePath, limit))
 	if err != nil {
 		return err
 	}
-
-start:
-	for i, pod := range root.Dig("items").AsArray() {
-		if i >= limit {
-			pod.Suicide()
-			goto start
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
 		}
-	}
+	}()
 
-	err = ioutil.WriteFile(fmt.Sprintf("%s-%d", filePath, limit), root.EncodeToByte(), 0644)
+	w, err := source.NewListWriter(out)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	for i := 0; i < limit; i++ {
+		item, err := items.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = w.Write(item); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
 }
@@ -0,0 +1,38 @@
+package _import
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCutPods(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "pods.json")
+	err := ioutil.WriteFile(filePath, []byte(`{"kind": "List", "items": [
+		{"metadata": {"name": "pod1"}}, {"metadata": {"name": "pod2"}}, {"metadata": {"name": "pod3"}}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = CutPods(filePath, 2); err != nil {
+		t.Fatalf("CutPods() error = %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filePath + "-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := struct {
+		Items []json.RawMessage `json:"items"`
+	}{}
+	if err = json.Unmarshal(b, &list); err != nil {
+		t.Fatalf("CutPods() wrote invalid json: %v: %s", err, b)
+	}
+
+	if len(list.Items) != 2 {
+		t.Errorf("CutPods() kept %d pods, want 2", len(list.Items))
+	}
+}
@@ -0,0 +1,221 @@
+package _import
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// ImportOptions configures import concurrency and logging
+type ImportOptions struct {
+	// Workers is a number of concurrent requests to simulator, values below 2 mean sequential import
+	Workers int
+	// RateLimit is a max number of requests per second, 0 - no limit
+	RateLimit float64
+	// Burst is a number of requests allowed above RateLimit at once
+	Burst int
+	// LogEnabled enables logging of every imported or skipped item
+	LogEnabled bool
+	// Format is an input file format, detected by file extension and content by default
+	Format source.Format
+}
+
+// ImportErrors aggregates errors of all import workers
+type ImportErrors []error
+
+func (e ImportErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d import errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Mutator changes an item before import, mutators run after importer's own cleanup in order they were added
+type Mutator func(item *insaneJSON.Node)
+
+// itemImporter is a filter and sender of items of one kind
+type itemImporter interface {
+	// accept decides if item should be imported and counts it against limits, returns skip reason otherwise
+	accept(item *insaneJSON.Node) (bool, string)
+	// prepare cleans up item for import and encodes it
+	prepare(item *insaneJSON.Node) []byte
+	// send pushes prepared item to simulator
+	send(ctx context.Context, payload []byte) (*http.Response, error)
+	// importedCount returns number of items successfully sent
+	importedCount() int
+	// skipped returns number of skipped items per rule
+	skipped() map[string]int
+}
+
+type importJob struct {
+	handled int
+	name    string
+	payload []byte
+}
+
+// importItems decodes, filters and prepares items sequentially and sends them to simulator with a pool of workers.
+// Only prepared payloads are kept in memory while items wait for workers.
+// The first failure stops dispatching, errors of all workers are returned as ImportErrors.
+func importItems(ctx context.Context, imp itemImporter, kind string, items source.Source, opts ImportOptions) error {
+	workersCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs ImportErrors
+	)
+
+	jobs := make(chan importJob)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				if err := sendItem(workersCtx, imp, job.payload); err != nil {
+					// errors of requests cancelled because of another worker failure are not interesting
+					if workersCtx.Err() == nil || !errors.Is(err, context.Canceled) {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("%s %s: %w", kind, job.name, err))
+						mu.Unlock()
+					}
+					cancel()
+					continue
+				}
+
+				if opts.LogEnabled {
+					log.Printf("Imported %s: %s (imported: %d, handled: %d)", kind, job.name, imp.importedCount(), job.handled)
+				}
+			}
+		}()
+	}
+
+	limiter := newRateLimiter(opts.RateLimit, opts.Burst)
+
+	var dispatchErr error
+
+dispatch:
+	for i := 0; ; i++ {
+		job, err := nextJob(imp, kind, items, i, opts.LogEnabled)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			dispatchErr = err
+			break
+		}
+		if job == nil {
+			continue
+		}
+
+		if err = limiter.Wait(workersCtx); err != nil {
+			break
+		}
+
+		select {
+		case jobs <- *job:
+		case <-workersCtx.Done():
+			break dispatch
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	logSkipped(imp, kind)
+
+	if dispatchErr != nil {
+		errs = append(errs, dispatchErr)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return ctx.Err()
+}
+
+// nextJob reads next item and prepares it for import, nil job means the item is skipped.
+// Item is released right after preparation, io.EOF is returned when there are no more items.
+func nextJob(imp itemImporter, kind string, items source.Source, handled int, logEnabled bool) (*importJob, error) {
+	b, err := items.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := insaneJSON.DecodeBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s #%d: %w", kind, handled, err)
+	}
+	defer insaneJSON.Release(root)
+
+	name := copyString(root.Dig("metadata").Dig("name").AsString())
+
+	if ok, reason := imp.accept(root.Node); !ok {
+		if logEnabled {
+			log.Printf("Skip %s: %s (%s)", kind, name, reason)
+		}
+		return nil, nil
+	}
+
+	return &importJob{handled: handled, name: name, payload: imp.prepare(root.Node)}, nil
+}
+
+// logSkipped logs number of skipped items per rule
+func logSkipped(imp itemImporter, kind string) {
+	skipped := imp.skipped()
+	if len(skipped) == 0 {
+		return
+	}
+
+	rules := make([]string, 0, len(skipped))
+	total := 0
+	for rule, n := range skipped {
+		rules = append(rules, fmt.Sprintf("%s=%d", rule, n))
+		total += n
+	}
+	sort.Strings(rules)
+
+	log.Printf("Skipped %d %ss: %s", total, kind, strings.Join(rules, ", "))
+}
+
+// copyString detaches string from insaneJSON decoder buffer, which is reused after Release,
+// so strings kept after item is released must be copied
+func copyString(s string) string {
+	return string(append([]byte(nil), s...))
+}
+
+func sendItem(ctx context.Context, imp itemImporter, payload []byte) error {
+	resp, err := imp.send(ctx, payload)
+	if err != nil {
+		return err
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		_ = resp.Body.Close()
+		return fmt.Errorf("got scheduler response status: %d: %s", resp.StatusCode, b)
+	}
+
+	return resp.Body.Close()
+}
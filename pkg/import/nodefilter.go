@@ -0,0 +1,260 @@
+package _import
+
+import (
+	"fmt"
+	"strings"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	labelInstanceType     = "node.kubernetes.io/instance-type"
+	labelInstanceTypeBeta = "beta.kubernetes.io/instance-type"
+	labelZone             = "topology.kubernetes.io/zone"
+	labelZoneBeta         = "failure-domain.beta.kubernetes.io/zone"
+)
+
+// NodeFilter is a nodes import filter spec, every non-empty field is a rule and a node is imported only if it passes all rules
+type NodeFilter struct {
+	// Selector is a label selector in kubectl syntax, e.g. "node-role.kubernetes.io/worker,pool!=gpu"
+	Selector string `yaml:"selector"`
+	// Taints imports only nodes having all given taints, ExcludeTaints skips nodes having any of them,
+	// taint is key, key=value, key:Effect or key=value:Effect
+	Taints        []string `yaml:"taints"`
+	ExcludeTaints []string `yaml:"excludeTaints"`
+	// CPU and Memory are ranges of node allocatable resources
+	CPU    QuantityRange `yaml:"cpu"`
+	Memory QuantityRange `yaml:"memory"`
+	// InstanceTypes and Zones import only nodes with given instance type and zone labels
+	InstanceTypes []string `yaml:"instanceTypes"`
+	Zones         []string `yaml:"zones"`
+	// CoresEq imports only nodes with given allocatable cpu cores, 0 - any
+	CoresEq int `yaml:"coresEq"`
+	// Stratify keeps proportion of nodes of every stratum when import is limited:
+	// shape (allocatable cpu and memory), instanceType, zone or label:<key>, empty - first nodes are imported
+	Stratify string `yaml:"stratify"`
+}
+
+// nodeFilter is a compiled NodeFilter
+type nodeFilter struct {
+	rules   rules
+	stratum func(node *insaneJSON.Node) string
+}
+
+// taint is a parsed taint spec, empty value and effect match any
+type taint struct {
+	key, value, effect string
+	hasValue           bool
+}
+
+// Validate checks that selector, taints, quantities and stratification key can be parsed
+func (f NodeFilter) Validate() error {
+	if f.CoresEq < 0 {
+		return fmt.Errorf("coresEq: must not be negative")
+	}
+
+	_, err := f.compile()
+	return err
+}
+
+func (f NodeFilter) compile() (*nodeFilter, error) {
+	nf := &nodeFilter{}
+
+	if f.Selector != "" {
+		selector, err := labels.Parse(f.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("selector: %w", err)
+		}
+
+		nf.rules.add("selector", func(node *insaneJSON.Node) string {
+			if !selector.Matches(objectLabels(node)) {
+				return fmt.Sprintf("labels do not match %q", selector)
+			}
+			return ""
+		})
+	}
+
+	if len(f.Taints) > 0 {
+		required, err := parseTaints(f.Taints)
+		if err != nil {
+			return nil, fmt.Errorf("taints: %w", err)
+		}
+
+		nf.rules.add("taints", func(node *insaneJSON.Node) string {
+			for i, t := range required {
+				if !hasTaint(node, t) {
+					return fmt.Sprintf("no taint %s", f.Taints[i])
+				}
+			}
+			return ""
+		})
+	}
+
+	if len(f.ExcludeTaints) > 0 {
+		excluded, err := parseTaints(f.ExcludeTaints)
+		if err != nil {
+			return nil, fmt.Errorf("excludeTaints: %w", err)
+		}
+
+		nf.rules.add("excludeTaints", func(node *insaneJSON.Node) string {
+			for i, t := range excluded {
+				if hasTaint(node, t) {
+					return fmt.Sprintf("taint %s is excluded", f.ExcludeTaints[i])
+				}
+			}
+			return ""
+		})
+	}
+
+	err := nf.rules.addRange("cpu", "allocatable cpu", f.CPU, func(node *insaneJSON.Node) resource.Quantity {
+		return nodeAllocatable(node, "cpu")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = nf.rules.addRange("memory", "allocatable memory", f.Memory, func(node *insaneJSON.Node) resource.Quantity {
+		return nodeAllocatable(node, "memory")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.InstanceTypes) > 0 {
+		include := toSet(f.InstanceTypes)
+		nf.rules.add("instanceTypes", func(node *insaneJSON.Node) string {
+			if it := nodeInstanceType(node); !include[it] {
+				return fmt.Sprintf("instance type %q is not included", it)
+			}
+			return ""
+		})
+	}
+
+	if len(f.Zones) > 0 {
+		include := toSet(f.Zones)
+		nf.rules.add("zones", func(node *insaneJSON.Node) string {
+			if zone := nodeZone(node); !include[zone] {
+				return fmt.Sprintf("zone %q is not included", zone)
+			}
+			return ""
+		})
+	}
+
+	if f.CoresEq != 0 {
+		nf.rules.add("coresEq", func(node *insaneJSON.Node) string {
+			if cores := node.Dig("status").Dig("allocatable").Dig("cpu").AsInt(); cores != f.CoresEq {
+				return fmt.Sprintf("allocatable cpu %d != %d", cores, f.CoresEq)
+			}
+			return ""
+		})
+	}
+
+	stratum, err := parseStratify(f.Stratify)
+	if err != nil {
+		return nil, err
+	}
+	nf.stratum = stratum
+
+	return nf, nil
+}
+
+// parseStratify returns node stratum key func, nil if stratification is off
+func parseStratify(stratify string) (func(node *insaneJSON.Node) string, error) {
+	switch {
+	case stratify == "":
+		return nil, nil
+	case stratify == "shape":
+		return nodeShape, nil
+	case stratify == "instanceType":
+		return nodeInstanceType, nil
+	case stratify == "zone":
+		return nodeZone, nil
+	case strings.HasPrefix(stratify, "label:") && len(stratify) > len("label:"):
+		key := strings.TrimPrefix(stratify, "label:")
+		return func(node *insaneJSON.Node) string {
+			return node.Dig("metadata", "labels", key).AsString()
+		}, nil
+	}
+
+	return nil, fmt.Errorf("stratify: unknown stratum key %q, want shape, instanceType, zone or label:<key>", stratify)
+}
+
+// parseTaints parses key, key=value, key:Effect and key=value:Effect taint specs
+func parseTaints(specs []string) ([]taint, error) {
+	out := make([]taint, 0, len(specs))
+	for _, spec := range specs {
+		t := taint{}
+
+		rest := spec
+		if i := strings.LastIndexByte(rest, ':'); i >= 0 {
+			rest, t.effect = rest[:i], rest[i+1:]
+		}
+		if i := strings.IndexByte(rest, '='); i >= 0 {
+			rest, t.value, t.hasValue = rest[:i], rest[i+1:], true
+		}
+		t.key = rest
+
+		if t.key == "" {
+			return nil, fmt.Errorf("taint %q has no key", spec)
+		}
+
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+func hasTaint(node *insaneJSON.Node, t taint) bool {
+	for _, nt := range node.Dig("spec", "taints").AsArray() {
+		if nt.Dig("key").AsString() != t.key {
+			continue
+		}
+		if t.hasValue && nt.Dig("value").AsString() != t.value {
+			continue
+		}
+		if t.effect != "" && nt.Dig("effect").AsString() != t.effect {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// nodeAllocatable returns allocatable resource quantity, unparsable quantity is zero
+func nodeAllocatable(node *insaneJSON.Node, res string) resource.Quantity {
+	q, err := resource.ParseQuantity(node.Dig("status", "allocatable", res).AsString())
+	if err != nil {
+		return resource.Quantity{}
+	}
+
+	return q
+}
+
+// nodeShape is allocatable cpu and memory rounded to GiB, kubelet reservations make memory of same machines differ slightly
+func nodeShape(node *insaneJSON.Node) string {
+	cpu := nodeAllocatable(node, "cpu")
+	mem := nodeAllocatable(node, "memory")
+
+	return fmt.Sprintf("cpu=%s,memory=%dGi", cpu.String(), (mem.Value()+(1<<29))>>30)
+}
+
+func nodeInstanceType(node *insaneJSON.Node) string {
+	return labelWithFallback(node, labelInstanceType, labelInstanceTypeBeta)
+}
+
+func nodeZone(node *insaneJSON.Node) string {
+	return labelWithFallback(node, labelZone, labelZoneBeta)
+}
+
+func labelWithFallback(node *insaneJSON.Node, key, fallback string) string {
+	nodeLabels := node.Dig("metadata", "labels")
+	if v := nodeLabels.Dig(key); v != nil {
+		return v.AsString()
+	}
+
+	return nodeLabels.Dig(fallback).AsString()
+}
@@ -0,0 +1,203 @@
+package _import
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const filterTestNodes = `
+{"metadata": {"name": "small-a", "labels": {"pool": "web", "node.kubernetes.io/instance-type": "m5.xlarge", "topology.kubernetes.io/zone": "a"}}, "status": {"allocatable": {"cpu": "4", "memory": "15Gi"}}}
+{"metadata": {"name": "small-b", "labels": {"pool": "web", "beta.kubernetes.io/instance-type": "m5.xlarge", "failure-domain.beta.kubernetes.io/zone": "b"}}, "status": {"allocatable": {"cpu": "4", "memory": "15300Mi"}}}
+{"metadata": {"name": "big-a", "labels": {"pool": "db", "node.kubernetes.io/instance-type": "r5.4xlarge", "topology.kubernetes.io/zone": "a"}}, "spec": {"taints": [{"key": "dedicated", "value": "db", "effect": "NoSchedule"}]}, "status": {"allocatable": {"cpu": "16", "memory": "124Gi"}}}
+{"metadata": {"name": "gpu-b", "labels": {"pool": "gpu", "node.kubernetes.io/instance-type": "p3.2xlarge", "topology.kubernetes.io/zone": "b"}}, "spec": {"taints": [{"key": "nvidia.com/gpu", "effect": "NoExecute"}]}, "status": {"allocatable": {"cpu": "8", "memory": "60Gi"}}}
+`
+
+func importNodesFiltered(t *testing.T, limit int, filter NodeFilter, input string) ([]string, map[string]int) {
+	t.Helper()
+
+	c := &recordingClient{}
+	importer, err := NewNodeImporterWithFilter(c, limit, filter)
+	if err != nil {
+		t.Fatalf("NewNodeImporterWithFilter() error = %v", err)
+	}
+
+	src, err := source.New(bufio.NewReader(strings.NewReader(strings.TrimSpace(input))), source.FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ImportNodesFrom(context.Background(), importer, src, ImportOptions{}); err != nil {
+		t.Fatalf("ImportNodesFrom() error = %v", err)
+	}
+
+	names := []string{}
+	for _, b := range c.nodes {
+		root, err := insaneJSON.DecodeBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, copyString(root.Dig("metadata", "name").AsString()))
+		insaneJSON.Release(root)
+	}
+
+	return names, importer.SkippedNodes()
+}
+
+func TestNodeFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      NodeFilter
+		want        []string
+		wantSkipped map[string]int
+	}{
+		{
+			name:        "no rules",
+			want:        []string{"small-a", "small-b", "big-a", "gpu-b"},
+			wantSkipped: map[string]int{},
+		},
+		{
+			name:        "selector",
+			filter:      NodeFilter{Selector: "pool in (web, db)"},
+			want:        []string{"small-a", "small-b", "big-a"},
+			wantSkipped: map[string]int{"selector": 1},
+		},
+		{
+			name:        "taints",
+			filter:      NodeFilter{Taints: []string{"dedicated=db:NoSchedule"}},
+			want:        []string{"big-a"},
+			wantSkipped: map[string]int{"taints": 3},
+		},
+		{
+			name:        "exclude taints",
+			filter:      NodeFilter{ExcludeTaints: []string{"nvidia.com/gpu", "dedicated:NoExecute"}},
+			want:        []string{"small-a", "small-b", "big-a"},
+			wantSkipped: map[string]int{"excludeTaints": 1},
+		},
+		{
+			name:        "allocatable ranges",
+			filter:      NodeFilter{CPU: QuantityRange{Min: "4", Max: "8"}, Memory: QuantityRange{Max: "32Gi"}},
+			want:        []string{"small-a", "small-b"},
+			wantSkipped: map[string]int{"cpu": 1, "memory": 1},
+		},
+		{
+			name:        "instance types",
+			filter:      NodeFilter{InstanceTypes: []string{"m5.xlarge"}},
+			want:        []string{"small-a", "small-b"},
+			wantSkipped: map[string]int{"instanceTypes": 2},
+		},
+		{
+			name:        "zones",
+			filter:      NodeFilter{Zones: []string{"b"}},
+			want:        []string{"small-b", "gpu-b"},
+			wantSkipped: map[string]int{"zones": 2},
+		},
+		{
+			name:        "cores",
+			filter:      NodeFilter{CoresEq: 16},
+			want:        []string{"big-a"},
+			wantSkipped: map[string]int{"coresEq": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := importNodesFiltered(t, 0, tt.filter, filterTestNodes)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imported %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("skipped %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestNodeStratifiedSampling(t *testing.T) {
+	// 60 small, 30 big and 10 gpu nodes interleaved
+	lines := []string{}
+	sizes := map[string]int{}
+	for n := 0; n < 100; n++ {
+		shape := "small"
+		switch {
+		case n%10 == 9:
+			shape = "gpu"
+		case n%3 == 0:
+			shape = "big"
+		}
+		lines = append(lines, fmt.Sprintf(`{"metadata": {"name": "%s-%d", "labels": {"shape": "%s"}}}`, shape, n, shape))
+		sizes[shape]++
+	}
+	input := strings.Join(lines, "\n")
+
+	counts := func(names []string) map[string]int {
+		out := map[string]int{}
+		for _, name := range names {
+			out[name[:strings.IndexByte(name, '-')]]++
+		}
+		return out
+	}
+
+	got, skipped := importNodesFiltered(t, 10, NodeFilter{Stratify: "label:shape"}, input)
+
+	want := map[string]int{}
+	for shape, n := range sizes {
+		want[shape] = n / 10
+	}
+	if !reflect.DeepEqual(counts(got), want) {
+		t.Errorf("imported %v, want %v per stratum", counts(got), want)
+	}
+	if !reflect.DeepEqual(skipped, map[string]int{"stratify": 90}) {
+		t.Errorf("skipped %v, want stratify: 90", skipped)
+	}
+
+	again, _ := importNodesFiltered(t, 10, NodeFilter{Stratify: "label:shape"}, input)
+	if !reflect.DeepEqual(got, again) {
+		t.Errorf("sample is not reproducible: %v != %v", got, again)
+	}
+}
+
+func TestNodeStratifiedSamplingRemainders(t *testing.T) {
+	got, skipped := importNodesFiltered(t, 2, NodeFilter{Stratify: "shape", ExcludeTaints: []string{"nvidia.com/gpu"}}, filterTestNodes)
+
+	// small-a and small-b share shape, memory is rounded to GiB
+	if want := []string{"small-b", "big-a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("imported %v, want %v", got, want)
+	}
+	if want := map[string]int{"stratify": 1, "excludeTaints": 1}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
+
+func TestNodeFilterValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter NodeFilter
+	}{
+		{"selector", NodeFilter{Selector: "pool in (web"}},
+		{"taint", NodeFilter{Taints: []string{"=db"}}},
+		{"quantity", NodeFilter{Memory: QuantityRange{Max: "lots"}}},
+		{"cores", NodeFilter{CoresEq: -1}},
+		{"stratify", NodeFilter{Stratify: "rack"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); err == nil {
+				t.Error("Validate() error = nil, want error")
+			}
+		})
+	}
+
+	if err := (NodeFilter{Stratify: "label:pool", Taints: []string{"dedicated:NoSchedule"}}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -1,95 +1,123 @@
 package _import
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
+	"sync"
 
 	insaneJSON "github.com/vitkovskii/insane-json"
+
 	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
 )
 
-// ImportNodes exports nodes from given json file to kubernetes-scheduler-simulator
-func ImportNodes(importer *NodeImporter, filePath string, logEnabled bool) error {
-	contents, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	root, err := insaneJSON.DecodeBytes(contents)
+// ImportNodes exports nodes from given file or directory of manifests to kubernetes-scheduler-simulator,
+// input is read as a stream
+func ImportNodes(ctx context.Context, importer *NodeImporter, path string, opts ImportOptions) error {
+	src, err := source.Open(path, opts.Format)
 	if err != nil {
 		return err
 	}
+	defer src.Close()
 
-	items := root.Dig("items")
-
-	for i, item := range items.AsArray() {
-		if importer.NeedSkipNode(item) {
-			if logEnabled {
-				log.Printf("Skip node: %s", item.Dig("metadata").Dig("name").AsString())
-			}
-			continue
-		}
-
-		resp, err := importer.Import(item)
-		if err != nil {
-			return err
-		}
-		b, _ := ioutil.ReadAll(resp.Body)
-		if resp.StatusCode >= http.StatusMultipleChoices {
-			return fmt.Errorf("got scheduler response status: %d: %s", resp.StatusCode, b)
-		}
+	return ImportNodesFrom(ctx, importer, src, opts)
+}
 
-		err = resp.Body.Close()
+// ImportNodesFrom exports nodes read from source to kubernetes-scheduler-simulator.
+// Stratified import reads all nodes into memory first to choose the sample.
+func ImportNodesFrom(ctx context.Context, importer *NodeImporter, src source.Source, opts ImportOptions) error {
+	if importer.filter.stratum != nil && importer.importNodesLimit > 0 {
+		items, err := importer.sample(src)
 		if err != nil {
 			return err
 		}
-
-		if logEnabled {
-			log.Printf(
-				"Imported node: %s (imported: %d, handled: %d)",
-				item.Dig("metadata").Dig("name").AsString(),
-				importer.ImportedNodesCount(),
-				i,
-			)
-		}
+		src = source.NewMemorySource(items)
 	}
 
-	insaneJSON.Release(root)
+	return importItems(ctx, importer, "node", src, opts)
+}
+
+// NewNodeImporter returns new node importer skipping nodes with allocatable cpu cores not equal to given, 0 - any
+func NewNodeImporter(c client.SimulatorClient, limit, skipNodeWithCoresNotEq int) *NodeImporter {
+	// filter with cores rule only always compiles
+	i, _ := NewNodeImporterWithFilter(c, limit, NodeFilter{CoresEq: skipNodeWithCoresNotEq})
 
-	return nil
+	return i
 }
 
-// NewNodeImporter returns new node importer
-func NewNodeImporter(c *client.HTTPClient, limit, skipNodeWithCoresNotEq int) *NodeImporter {
-	return &NodeImporter{
-		c:                      c,
-		importNodesLimit:       limit,
-		skipNodeWithCoresNotEq: skipNodeWithCoresNotEq,
+// NewNodeImporterWithFilter returns new node importer with filter rules, limit is a global import limit, 0 - no limit
+func NewNodeImporterWithFilter(c client.SimulatorClient, limit int, filter NodeFilter) (*NodeImporter, error) {
+	nf, err := filter.compile()
+	if err != nil {
+		return nil, err
 	}
+
+	return &NodeImporter{
+		c:                c,
+		filter:           nf,
+		importNodesLimit: limit,
+		skippedNodes:     map[string]int{},
+	}, nil
 }
 
 // NodeImporter is a filter for importing nodes
 type NodeImporter struct {
-	c *client.HTTPClient
+	c client.SimulatorClient
+
+	mu                 sync.Mutex
+	filter             *nodeFilter
+	importNodesLimit   int
+	acceptedNodesCount int
+	importedNodesCount int
+	skippedNodes       map[string]int
+
+	// sampled is a set of node names chosen by stratified sampling, nil if import is not stratified
+	sampled map[string]bool
+
+	mutators []Mutator
+}
+
+// AddMutators adds hooks applied to every node after prepareNode cleanup
+func (i *NodeImporter) AddMutators(m ...Mutator) *NodeImporter {
+	i.mutators = append(i.mutators, m...)
 
-	skipNodeWithCoresNotEq int
-	importNodesLimit       int
-	importedNodesCount     int
+	return i
 }
 
 // NeedSkipNode decides if we want to skip the node
 func (i *NodeImporter) NeedSkipNode(node *insaneJSON.Node) bool {
-	if i.importNodesLimit > 0 && i.importedNodesCount >= i.importNodesLimit {
-		return true
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rule, _ := i.skipReason(node)
+
+	return rule != ""
+}
+
+// skipReason returns the rule which skips the node and the reason, empty rule if node is accepted
+func (i *NodeImporter) skipReason(node *insaneJSON.Node) (string, string) {
+	limitReached := i.importNodesLimit > 0 && i.acceptedNodesCount >= i.importNodesLimit
+
+	// sample already fits the limit, so nodes are skipped by their own rules rather than by the limit
+	if limitReached && i.sampled == nil {
+		return "limit", fmt.Sprintf("import limit %d reached", i.importNodesLimit)
 	}
 
-	if i.skipNodeWithCoresNotEq != 0 && i.skipNodeWithCoresNotEq != node.Dig("status").Dig("allocatable").Dig("cpu").AsInt() {
-		return true
+	if rule, reason := i.filter.rules.skipReason(node); rule != "" {
+		return rule, reason
 	}
 
-	return false
+	if i.sampled != nil && !i.sampled[node.Dig("metadata", "name").AsString()] {
+		return "stratify", fmt.Sprintf("not sampled from stratum %q", i.filter.stratum(node))
+	}
+
+	// nodes with duplicate names may be sampled more than once
+	if limitReached {
+		return "limit", fmt.Sprintf("import limit %d reached", i.importNodesLimit)
+	}
+
+	return "", ""
 }
 
 func (i *NodeImporter) prepareNode(node *insaneJSON.Node) *insaneJSON.Node {
@@ -99,22 +127,76 @@ func (i *NodeImporter) prepareNode(node *insaneJSON.Node) *insaneJSON.Node {
 	// remove status.images to avoid image locality scoring
 	node.Dig("status").Dig("images").Suicide()
 
+	for _, m := range i.mutators {
+		m(node)
+	}
+
 	return node
 }
 
 // Import imports node
-func (i *NodeImporter) Import(node *insaneJSON.Node) (*http.Response, error) {
-	node = i.prepareNode(node)
+func (i *NodeImporter) Import(ctx context.Context, node *insaneJSON.Node) (*http.Response, error) {
+	i.mu.Lock()
+	i.acceptedNodesCount++
+	i.mu.Unlock()
+
+	return i.send(ctx, i.prepare(node))
+}
+
+// ImportedNodesCount returns imported nodes count
+func (i *NodeImporter) ImportedNodesCount() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.importedNodesCount
+}
+
+func (i *NodeImporter) accept(node *insaneJSON.Node) (bool, string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if rule, reason := i.skipReason(node); rule != "" {
+		i.skippedNodes[rule]++
+		return false, rule + ": " + reason
+	}
 
-	resp, err := i.c.ApplyNodes(node.EncodeToByte())
+	i.acceptedNodesCount++
+
+	return true, ""
+}
+
+func (i *NodeImporter) prepare(node *insaneJSON.Node) []byte {
+	return i.prepareNode(node).EncodeToByte()
+}
+
+func (i *NodeImporter) send(ctx context.Context, payload []byte) (*http.Response, error) {
+	resp, err := i.c.ApplyNodes(ctx, payload)
 	if err == nil {
+		i.mu.Lock()
 		i.importedNodesCount++
+		i.mu.Unlock()
 	}
 
 	return resp, err
 }
 
-// ImportedNodesCount returns imported nodes count
-func (i *NodeImporter) ImportedNodesCount() int {
-	return i.importedNodesCount
+func (i *NodeImporter) importedCount() int {
+	return i.ImportedNodesCount()
+}
+
+func (i *NodeImporter) skipped() map[string]int {
+	return i.SkippedNodes()
+}
+
+// SkippedNodes returns skipped nodes count per rule
+func (i *NodeImporter) SkippedNodes() map[string]int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := make(map[string]int, len(i.skippedNodes))
+	for rule, n := range i.skippedNodes {
+		out[rule] = n
+	}
+
+	return out
 }
@@ -0,0 +1,103 @@
+package _import
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// recordingClient is a SimulatorClient which records applied objects
+type recordingClient struct {
+	nodes [][]byte
+	pods  [][]byte
+}
+
+func (c *recordingClient) ApplyNodes(_ context.Context, nodesJSON []byte) (*http.Response, error) {
+	c.nodes = append(c.nodes, nodesJSON)
+	return okResponse(), nil
+}
+
+func (c *recordingClient) ApplyPods(_ context.Context, podJSON []byte) (*http.Response, error) {
+	c.pods = append(c.pods, podJSON)
+	return okResponse(), nil
+}
+
+func (c *recordingClient) ApplyConfig(context.Context, []byte) (*http.Response, error) {
+	return okResponse(), nil
+}
+func (c *recordingClient) Reset(context.Context) (*http.Response, error)    { return okResponse(), nil }
+func (c *recordingClient) ListPods(context.Context) (*http.Response, error) { return okResponse(), nil }
+func (c *recordingClient) ListNodes(context.Context) (*http.Response, error) {
+	return okResponse(), nil
+}
+
+func okResponse() *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("{}"))}
+}
+
+func TestImportNodes(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "nodes.json")
+	err := ioutil.WriteFile(filePath, []byte(`{"items": [
+		{"metadata": {"name": "node1", "uid": "1"}, "status": {"allocatable": {"cpu": "88"}, "images": []}},
+		{"metadata": {"name": "node2", "uid": "2"}, "status": {"allocatable": {"cpu": "32"}}},
+		{"metadata": {"name": "node3", "uid": "3"}, "status": {"allocatable": {"cpu": "88"}}},
+		{"metadata": {"name": "node4", "uid": "4"}, "status": {"allocatable": {"cpu": "88"}}}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &recordingClient{}
+	importer := NewNodeImporter(c, 2, 88)
+
+	if err = ImportNodes(context.Background(), importer, filePath, ImportOptions{}); err != nil {
+		t.Fatalf("ImportNodes() error = %v", err)
+	}
+
+	if len(c.nodes) != 2 || importer.ImportedNodesCount() != 2 {
+		t.Fatalf("ImportNodes() imported %d nodes, want 2", len(c.nodes))
+	}
+
+	node, err := insaneJSON.DecodeBytes(c.nodes[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insaneJSON.Release(node)
+
+	if name := node.Dig("metadata", "name").AsString(); name != "node3" {
+		t.Errorf("ImportNodes() second node = %s, want node3", name)
+	}
+	if node.Dig("metadata", "uid") != nil || node.Dig("status", "images") != nil {
+		t.Errorf("ImportNodes() node is not prepared for import: %s", c.nodes[1])
+	}
+}
+
+func TestImportNodesMutators(t *testing.T) {
+	c := &recordingClient{}
+	importer := NewNodeImporter(c, 0, 0).AddMutators(
+		func(node *insaneJSON.Node) { node.Dig("metadata", "name").MutateToString("renamed") },
+		func(node *insaneJSON.Node) { node.Dig("metadata").AddField("uid").MutateToString("mutated") },
+	)
+
+	src, err := source.New(bufio.NewReader(strings.NewReader(`{"metadata": {"name": "node1", "uid": "1"}}`)), source.FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ImportNodesFrom(context.Background(), importer, src, ImportOptions{}); err != nil {
+		t.Fatalf("ImportNodesFrom() error = %v", err)
+	}
+
+	// uid is removed by prepareNode before mutators run
+	if want := `{"metadata":{"name":"renamed","uid":"mutated"}}`; len(c.nodes) != 1 || string(c.nodes[0]) != want {
+		t.Errorf("ImportNodesFrom() imported %s, want %s", c.nodes, want)
+	}
+}
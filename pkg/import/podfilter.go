@@ -0,0 +1,243 @@
+package _import
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// DefaultGroupBy is the pods grouping key for per group caps
+const DefaultGroupBy = "label:service"
+
+// PodFilter is a pods import filter spec, every non-empty field is a rule and a pod is imported only if it passes all rules
+type PodFilter struct {
+	// Namespaces imports only pods of given namespaces
+	Namespaces []string `yaml:"namespaces"`
+	// ExcludeNamespaces skips pods of given namespaces
+	ExcludeNamespaces []string `yaml:"excludeNamespaces"`
+	// Selector is a label selector in kubectl syntax, e.g. "app in (a, b), tier!=cache"
+	Selector string `yaml:"selector"`
+	// ExcludeOwnerKinds skips pods owned by given kinds, e.g. DaemonSet, Job
+	ExcludeOwnerKinds []string `yaml:"excludeOwnerKinds"`
+	// Phases imports only pods in given phases
+	Phases []string `yaml:"phases"`
+	// PriorityClasses imports only pods with given priority class names
+	PriorityClasses []string `yaml:"priorityClasses"`
+	// CPU and Memory are ranges of effective pod requests with init containers, sidecars and overhead, see PodRequests
+	CPU    QuantityRange `yaml:"cpu"`
+	Memory QuantityRange `yaml:"memory"`
+	// GroupBy is a grouping key for MaxPerGroup: label:<key>, owner or namespace, label:service by default
+	GroupBy string `yaml:"groupBy"`
+	// MaxPerGroup caps imported pods per group, pods without group are not capped, 0 - no cap
+	MaxPerGroup int `yaml:"maxPerGroup"`
+}
+
+// podFilter is a compiled PodFilter
+type podFilter struct {
+	rules       rules
+	groupKey    func(pod *insaneJSON.Node) string
+	maxPerGroup int
+}
+
+// Validate checks that selector, quantities and grouping key can be parsed
+func (f PodFilter) Validate() error {
+	if f.MaxPerGroup < 0 {
+		return fmt.Errorf("maxPerGroup: must not be negative")
+	}
+
+	_, err := f.compile()
+	return err
+}
+
+func (f PodFilter) compile() (*podFilter, error) {
+	pf := &podFilter{maxPerGroup: f.MaxPerGroup}
+
+	if len(f.Namespaces) > 0 {
+		include := toSet(f.Namespaces)
+		pf.rules.add("namespaces", func(pod *insaneJSON.Node) string {
+			if ns := podNamespace(pod); !include[ns] {
+				return fmt.Sprintf("namespace %q is not included", ns)
+			}
+			return ""
+		})
+	}
+
+	if len(f.ExcludeNamespaces) > 0 {
+		exclude := toSet(f.ExcludeNamespaces)
+		pf.rules.add("excludeNamespaces", func(pod *insaneJSON.Node) string {
+			if ns := podNamespace(pod); exclude[ns] {
+				return fmt.Sprintf("namespace %q is excluded", ns)
+			}
+			return ""
+		})
+	}
+
+	if f.Selector != "" {
+		selector, err := labels.Parse(f.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("selector: %w", err)
+		}
+
+		pf.rules.add("selector", func(pod *insaneJSON.Node) string {
+			if !selector.Matches(objectLabels(pod)) {
+				return fmt.Sprintf("labels do not match %q", selector)
+			}
+			return ""
+		})
+	}
+
+	if len(f.ExcludeOwnerKinds) > 0 {
+		exclude := toSet(f.ExcludeOwnerKinds)
+		pf.rules.add("excludeOwnerKinds", func(pod *insaneJSON.Node) string {
+			for _, ref := range pod.Dig("metadata", "ownerReferences").AsArray() {
+				if kind := ref.Dig("kind").AsString(); exclude[kind] {
+					return fmt.Sprintf("owned by %s", kind)
+				}
+			}
+			return ""
+		})
+	}
+
+	if len(f.Phases) > 0 {
+		include := toSet(f.Phases)
+		pf.rules.add("phases", func(pod *insaneJSON.Node) string {
+			if phase := pod.Dig("status", "phase").AsString(); !include[phase] {
+				return fmt.Sprintf("phase %q is not included", phase)
+			}
+			return ""
+		})
+	}
+
+	if len(f.PriorityClasses) > 0 {
+		include := toSet(f.PriorityClasses)
+		pf.rules.add("priorityClasses", func(pod *insaneJSON.Node) string {
+			if pc := pod.Dig("spec", "priorityClassName").AsString(); !include[pc] {
+				return fmt.Sprintf("priority class %q is not included", pc)
+			}
+			return ""
+		})
+	}
+
+	err := pf.rules.addRange("cpu", "cpu requests", f.CPU, func(pod *insaneJSON.Node) resource.Quantity {
+		effective, _ := PodRequests(pod)
+		return *resource.NewMilliQuantity(effective["cpu"], resource.DecimalSI)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = pf.rules.addRange("memory", "memory requests", f.Memory, func(pod *insaneJSON.Node) resource.Quantity {
+		effective, _ := PodRequests(pod)
+		return *resource.NewQuantity(effective["memory"], resource.BinarySI)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groupKey, err := ParseGroupBy(f.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	pf.groupKey = groupKey
+
+	return pf, nil
+}
+
+// Each calls fn for every pod of source passing filter rules with its group key, MaxPerGroup cap is not applied
+func (f PodFilter) Each(src source.Source, fn func(pod []byte, group string) error) error {
+	pf, err := f.compile()
+	if err != nil {
+		return err
+	}
+
+	for n := 0; ; n++ {
+		b, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		root, err := insaneJSON.DecodeBytes(b)
+		if err != nil {
+			return fmt.Errorf("pod #%d: %w", n, err)
+		}
+
+		rule, _ := pf.skipReason(root.Node)
+		group := copyString(pf.groupKey(root.Node))
+		insaneJSON.Release(root)
+
+		if rule != "" {
+			continue
+		}
+		if err = fn(b, group); err != nil {
+			return err
+		}
+	}
+}
+
+// skipReason returns name of the first rule the pod does not pass and the reason, empty name if pod passes all rules
+func (pf *podFilter) skipReason(pod *insaneJSON.Node) (string, string) {
+	return pf.rules.skipReason(pod)
+}
+
+// ParseGroupBy returns pod grouping key func: label:<key>, owner (kind/name of controller) or namespace,
+// DefaultGroupBy if groupBy is empty
+func ParseGroupBy(groupBy string) (func(pod *insaneJSON.Node) string, error) {
+	if groupBy == "" {
+		groupBy = DefaultGroupBy
+	}
+
+	switch {
+	case groupBy == "namespace":
+		return podNamespace, nil
+	case groupBy == "owner":
+		return podOwner, nil
+	case strings.HasPrefix(groupBy, "label:") && len(groupBy) > len("label:"):
+		key := strings.TrimPrefix(groupBy, "label:")
+		return func(pod *insaneJSON.Node) string {
+			return pod.Dig("metadata", "labels", key).AsString()
+		}, nil
+	}
+
+	return nil, fmt.Errorf("groupBy: unknown grouping key %q, want label:<key>, owner or namespace", groupBy)
+}
+
+func podNamespace(pod *insaneJSON.Node) string {
+	return pod.Dig("metadata", "namespace").AsString()
+}
+
+// podOwner returns kind/name of pod controller, first owner if there is no controller
+func podOwner(pod *insaneJSON.Node) string {
+	refs := pod.Dig("metadata", "ownerReferences").AsArray()
+	if len(refs) == 0 {
+		return ""
+	}
+
+	owner := refs[0]
+	for _, ref := range refs {
+		if ref.Dig("controller").IsTrue() {
+			owner = ref
+			break
+		}
+	}
+
+	return owner.Dig("kind").AsString() + "/" + owner.Dig("name").AsString()
+}
+
+// objectLabels returns metadata.labels of pod or node
+func objectLabels(obj *insaneJSON.Node) labels.Set {
+	set := labels.Set{}
+	for _, field := range obj.Dig("metadata", "labels").AsFields() {
+		set[field.AsString()] = field.AsFieldValue().AsString()
+	}
+
+	return set
+}
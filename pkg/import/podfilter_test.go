@@ -0,0 +1,171 @@
+package _import
+
+import (
+	"bufio"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+const filterTestPods = `
+{"metadata": {"name": "web-1", "namespace": "prod", "labels": {"app": "web", "tier": "front"}, "ownerReferences": [{"kind": "ReplicaSet", "name": "web-5d8", "controller": true}]}, "spec": {"priorityClassName": "high", "containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}, "status": {"phase": "Running"}}
+{"metadata": {"name": "web-2", "namespace": "prod", "labels": {"app": "web", "tier": "front"}, "ownerReferences": [{"kind": "ReplicaSet", "name": "web-5d8", "controller": true}]}, "spec": {"priorityClassName": "high", "containers": [{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}, "status": {"phase": "Running"}}
+{"metadata": {"name": "agent-x", "namespace": "prod", "labels": {"app": "agent"}, "ownerReferences": [{"kind": "DaemonSet", "name": "agent"}]}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m", "memory": "128Mi"}}}]}, "status": {"phase": "Running"}}
+{"metadata": {"name": "migrate-1", "namespace": "prod", "labels": {"app": "migrate"}, "ownerReferences": [{"kind": "Job", "name": "migrate"}]}, "spec": {"containers": [{"resources": {"requests": {"cpu": "1"}}}]}, "status": {"phase": "Succeeded"}}
+{"metadata": {"name": "db-1", "namespace": "data", "labels": {"app": "db"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "2", "memory": "8Gi"}}}, {"resources": {"requests": {"cpu": "2"}}}]}, "status": {"phase": "Running"}}
+{"metadata": {"name": "dns-1", "namespace": "kube-system", "labels": {"app": "dns"}}, "spec": {"containers": [{"resources": {"requests": {"cpu": "100m"}}}], "overhead": {"cpu": "150m", "memory": "1Gi"}}, "status": {"phase": "Running"}}
+`
+
+func importFiltered(t *testing.T, filter PodFilter) ([]string, map[string]int) {
+	t.Helper()
+
+	c := &recordingClient{}
+	importer, err := NewPodImporterWithFilter(c, 0, filter)
+	if err != nil {
+		t.Fatalf("NewPodImporterWithFilter() error = %v", err)
+	}
+
+	src, err := source.New(bufio.NewReader(strings.NewReader(strings.TrimSpace(filterTestPods))), source.FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ImportPodsFrom(context.Background(), importer, src, ImportOptions{LogEnabled: true}); err != nil {
+		t.Fatalf("ImportPodsFrom() error = %v", err)
+	}
+
+	names := []string{}
+	for _, b := range c.pods {
+		root, err := insaneJSON.DecodeBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, copyString(root.Dig("metadata", "name").AsString()))
+		insaneJSON.Release(root)
+	}
+
+	return names, importer.SkippedPods()
+}
+
+func TestPodFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      PodFilter
+		want        []string
+		wantSkipped map[string]int
+	}{
+		{
+			name:        "no rules",
+			want:        []string{"web-1", "web-2", "agent-x", "migrate-1", "db-1", "dns-1"},
+			wantSkipped: map[string]int{},
+		},
+		{
+			name:        "namespaces",
+			filter:      PodFilter{Namespaces: []string{"prod", "data"}, ExcludeNamespaces: []string{"data"}},
+			want:        []string{"web-1", "web-2", "agent-x", "migrate-1"},
+			wantSkipped: map[string]int{"namespaces": 1, "excludeNamespaces": 1},
+		},
+		{
+			name:        "selector",
+			filter:      PodFilter{Selector: "app in (web, db), tier!=front"},
+			want:        []string{"db-1"},
+			wantSkipped: map[string]int{"selector": 5},
+		},
+		{
+			name:        "owner kinds and phases",
+			filter:      PodFilter{ExcludeOwnerKinds: []string{"DaemonSet"}, Phases: []string{"Running"}},
+			want:        []string{"web-1", "web-2", "db-1", "dns-1"},
+			wantSkipped: map[string]int{"excludeOwnerKinds": 1, "phases": 1},
+		},
+		{
+			name:        "priority classes",
+			filter:      PodFilter{PriorityClasses: []string{"high"}},
+			want:        []string{"web-1", "web-2"},
+			wantSkipped: map[string]int{"priorityClasses": 4},
+		},
+		{
+			name:        "effective requests ranges",
+			filter:      PodFilter{CPU: QuantityRange{Min: "200m", Max: "4"}, Memory: QuantityRange{Min: "512Mi"}},
+			want:        []string{"web-1", "web-2", "db-1", "dns-1"},
+			wantSkipped: map[string]int{"cpu": 1, "memory": 1},
+		},
+		{
+			name:        "max per label group",
+			filter:      PodFilter{GroupBy: "label:app", MaxPerGroup: 1},
+			want:        []string{"web-1", "agent-x", "migrate-1", "db-1", "dns-1"},
+			wantSkipped: map[string]int{"maxPerGroup": 1},
+		},
+		{
+			name:        "max per owner",
+			filter:      PodFilter{GroupBy: "owner", MaxPerGroup: 1},
+			want:        []string{"web-1", "agent-x", "migrate-1", "db-1", "dns-1"},
+			wantSkipped: map[string]int{"maxPerGroup": 1},
+		},
+		{
+			name:        "max per namespace",
+			filter:      PodFilter{GroupBy: "namespace", MaxPerGroup: 2},
+			want:        []string{"web-1", "web-2", "db-1", "dns-1"},
+			wantSkipped: map[string]int{"maxPerGroup": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := importFiltered(t, tt.filter)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imported %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("skipped %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestPodImporterSkipReason(t *testing.T) {
+	importer, err := NewPodImporterWithFilter(&recordingClient{}, 0, PodFilter{ExcludeOwnerKinds: []string{"Job"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := insaneJSON.DecodeString(`{"metadata": {"name": "migrate-1", "ownerReferences": [{"kind": "Job", "name": "migrate"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insaneJSON.Release(root)
+
+	ok, reason := importer.accept(root.Node)
+	if ok || reason != "excludeOwnerKinds: owned by Job" {
+		t.Errorf("accept() = %v, %q, want skip by excludeOwnerKinds", ok, reason)
+	}
+}
+
+func TestPodFilterValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter PodFilter
+	}{
+		{"selector", PodFilter{Selector: "app in (web"}},
+		{"quantity", PodFilter{CPU: QuantityRange{Min: "lots"}}},
+		{"group by", PodFilter{GroupBy: "service"}},
+		{"max per group", PodFilter{MaxPerGroup: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); err == nil {
+				t.Error("Validate() error = nil, want error")
+			}
+		})
+	}
+
+	if err := (PodFilter{GroupBy: "label:app", Selector: "app"}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
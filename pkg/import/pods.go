@@ -1,135 +1,164 @@
 package _import
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
-	"time"
+	"sync"
 
 	insaneJSON "github.com/vitkovskii/insane-json"
+
 	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
 )
 
-// ImportPods exports pods from given json file to kubernetes-scheduler-simulator
-func ImportPods(importer *PodImporter, filePath string, logEnabled bool) error {
-	contents, err := ioutil.ReadFile(filePath)
+// ImportPods exports pods from given file or directory of manifests to kubernetes-scheduler-simulator,
+// input is read as a stream
+func ImportPods(ctx context.Context, importer *PodImporter, path string, opts ImportOptions) error {
+	src, err := source.Open(path, opts.Format)
 	if err != nil {
 		return err
 	}
+	defer src.Close()
 
-	root, err := insaneJSON.DecodeBytes(contents)
-	if err != nil {
-		return err
-	}
+	return ImportPodsFrom(ctx, importer, src, opts)
+}
 
-	items := root.Dig("items")
+// ImportPodsFrom exports pods read from source to kubernetes-scheduler-simulator
+func ImportPodsFrom(ctx context.Context, importer *PodImporter, src source.Source, opts ImportOptions) error {
+	return importItems(ctx, importer, "pod", src, opts)
+}
 
-	for i, pod := range items.AsArray() {
-		if importer.NeedSkipPod(pod) {
-			if logEnabled {
-				log.Printf("Skip pod: %s", pod.Dig("metadata").Dig("name").AsString())
-			}
-			continue
-		}
+// PodImporter is a filter for importing pods
+type PodImporter struct {
+	c client.SimulatorClient
 
-		if i > 0 && i%100 == 0 {
-			time.Sleep(1 * time.Second)
-		}
+	mu                sync.Mutex
+	filter            *podFilter
+	importPodsLimit   int
+	acceptedPodsCount int
+	importedPodsCount int
 
-		resp, err := importer.Import(pod)
-		if err != nil {
-			return err
-		}
-		b, _ := ioutil.ReadAll(resp.Body)
+	acceptedPodsPerGroup map[string]int
+	skippedPods          map[string]int
 
-		if resp.StatusCode >= http.StatusMultipleChoices {
-			return fmt.Errorf("got scheduler response status: %d: %s", resp.StatusCode, b)
-		}
+	mutators []Mutator
+}
 
-		err = resp.Body.Close()
-		if err != nil {
-			return err
-		}
+// NewPodImporter returns new pod importer capping pods per service label value, 0 - no cap
+func NewPodImporter(c client.SimulatorClient, limit, maxPodsPerService int) *PodImporter {
+	// filter with group cap only always compiles
+	i, _ := NewPodImporterWithFilter(c, limit, PodFilter{MaxPerGroup: maxPodsPerService})
 
-		if logEnabled {
-			log.Printf(
-				"Imported pod: %s (imported: %d, handled: %d)",
-				pod.Dig("metadata").Dig("name").AsString(),
-				importer.ImportedPodsCount(),
-				i,
-			)
-		}
+	return i
+}
+
+// NewPodImporterWithFilter returns new pod importer with filter rules, limit is a global import limit, 0 - no limit
+func NewPodImporterWithFilter(c client.SimulatorClient, limit int, filter PodFilter) (*PodImporter, error) {
+	pf, err := filter.compile()
+	if err != nil {
+		return nil, err
 	}
 
-	insaneJSON.Release(root)
+	return &PodImporter{
+		c:                    c,
+		filter:               pf,
+		importPodsLimit:      limit,
+		acceptedPodsPerGroup: map[string]int{},
+		skippedPods:          map[string]int{},
+	}, nil
+}
+
+// AddMutators adds hooks applied to every pod after preparePodForImport cleanup
+func (i *PodImporter) AddMutators(m ...Mutator) *PodImporter {
+	i.mutators = append(i.mutators, m...)
 
-	return nil
+	return i
 }
 
-// PodImporter is a filter for importing pods
-type PodImporter struct {
-	c *client.HTTPClient
+// NeedSkipPod decides if we want to skip the pod
+func (i *PodImporter) NeedSkipPod(pod *insaneJSON.Node) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 
-	maxPodsPerService int
-	importPodsLimit   int
-	importedPodsCount int
+	rule, _ := i.skipReason(pod)
 
-	importedPodsPerSvc map[string]int
+	return rule != ""
 }
 
-// NewPodImporter returns new pod importer
-func NewPodImporter(c *client.HTTPClient, limit, maxPodsPerService int) *PodImporter {
-	return &PodImporter{
-		c:                  c,
-		importPodsLimit:    limit,
-		maxPodsPerService:  maxPodsPerService,
-		importedPodsPerSvc: map[string]int{},
+// skipReason returns the rule which skips the pod and the reason, empty rule if pod is accepted
+func (i *PodImporter) skipReason(pod *insaneJSON.Node) (string, string) {
+	if i.importPodsLimit > 0 && i.acceptedPodsCount >= i.importPodsLimit {
+		return "limit", fmt.Sprintf("import limit %d reached", i.importPodsLimit)
 	}
-}
 
-// NeedSkipPod decides if we want to skip the pod
-func (i *PodImporter) NeedSkipPod(pod *insaneJSON.Node) bool {
-	if i.importPodsLimit > 0 && i.importedPodsCount >= i.importPodsLimit {
-		return true
+	if rule, reason := i.filter.skipReason(pod); rule != "" {
+		return rule, reason
 	}
 
-	svc := i.extractServiceName(pod)
-	if svc != "" {
-		if _, ok := i.importedPodsPerSvc[svc]; ok {
-			if i.importedPodsPerSvc[svc] >= i.maxPodsPerService {
-				return true
-			}
+	if i.filter.maxPerGroup > 0 {
+		group := i.filter.groupKey(pod)
+		if group != "" && i.acceptedPodsPerGroup[group] >= i.filter.maxPerGroup {
+			return "maxPerGroup", fmt.Sprintf("group %q already has %d pods", group, i.filter.maxPerGroup)
 		}
 	}
 
-	return false
+	return "", ""
 }
 
 // Import imports the pod
-func (i *PodImporter) Import(pod *insaneJSON.Node) (*http.Response, error) {
-	pod = i.preparePodForImport(pod)
+func (i *PodImporter) Import(ctx context.Context, pod *insaneJSON.Node) (*http.Response, error) {
+	i.mu.Lock()
+	i.countAccepted(pod)
+	i.mu.Unlock()
+
+	return i.send(ctx, i.prepare(pod))
+}
+
+func (i *PodImporter) accept(pod *insaneJSON.Node) (bool, string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if rule, reason := i.skipReason(pod); rule != "" {
+		i.skippedPods[rule]++
+		return false, rule + ": " + reason
+	}
+
+	i.countAccepted(pod)
+
+	return true, ""
+}
+
+// countAccepted counts pod against limits, must be called under lock
+func (i *PodImporter) countAccepted(pod *insaneJSON.Node) {
+	i.acceptedPodsCount++
+
+	if group := i.filter.groupKey(pod); group != "" {
+		i.acceptedPodsPerGroup[copyString(group)]++
+	}
+}
 
-	resp, err := i.c.ApplyPods(pod.EncodeToByte())
+func (i *PodImporter) prepare(pod *insaneJSON.Node) []byte {
+	return i.preparePodForImport(pod).EncodeToByte()
+}
+
+func (i *PodImporter) send(ctx context.Context, payload []byte) (*http.Response, error) {
+	resp, err := i.c.ApplyPods(ctx, payload)
 	if err == nil {
+		i.mu.Lock()
 		i.importedPodsCount++
-
-		svc := i.extractServiceName(pod)
-		if svc != "" {
-			if _, ok := i.importedPodsPerSvc[svc]; !ok {
-				i.importedPodsPerSvc[svc] = 1
-			} else {
-				i.importedPodsPerSvc[svc]++
-			}
-		}
+		i.mu.Unlock()
 	}
 
 	return resp, err
 }
 
-// extractServiceName returns service name
-func (i *PodImporter) extractServiceName(pod *insaneJSON.Node) string {
-	return pod.Dig("metadata").Dig("labels").Dig("service").AsString()
+func (i *PodImporter) importedCount() int {
+	return i.ImportedPodsCount()
+}
+
+func (i *PodImporter) skipped() map[string]int {
+	return i.SkippedPods()
 }
 
 // preparePodForImport cleans up excess pod data for import
@@ -141,6 +170,10 @@ func (i *PodImporter) preparePodForImport(pod *insaneJSON.Node) *insaneJSON.Node
 
 	i.deduplicateEnvVars(pod)
 
+	for _, m := range i.mutators {
+		m(pod)
+	}
+
 	return pod
 }
 
@@ -158,6 +191,8 @@ func (i *PodImporter) deduplicateEnvVars(pod *insaneJSON.Node) {
 
 				if _, ok := envVarKeys[envVarName]; ok {
 					envVar.Suicide()
+					// start over with fresh keys, otherwise the first occurrence is removed too
+					envVarKeys = map[string]bool{}
 					goto cycle
 				}
 
@@ -169,5 +204,21 @@ func (i *PodImporter) deduplicateEnvVars(pod *insaneJSON.Node) {
 
 // ImportedPodsCount returns imported pods count
 func (i *PodImporter) ImportedPodsCount() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	return i.importedPodsCount
 }
+
+// SkippedPods returns skipped pods count per rule
+func (i *PodImporter) SkippedPods() map[string]int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := make(map[string]int, len(i.skippedPods))
+	for rule, n := range i.skippedPods {
+		out[rule] = n
+	}
+
+	return out
+}
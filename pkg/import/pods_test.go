@@ -0,0 +1,142 @@
+package _import
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client/fake"
+)
+
+func TestImportPods(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "pods.json")
+	err := ioutil.WriteFile(filePath, []byte(`{"items": [
+		{"metadata": {"name": "a-1", "uid": "1", "namespace": "ns", "labels": {"service": "a"}}, "spec": {"nodeName": "prod1"}},
+		{"metadata": {"name": "a-2", "uid": "2", "namespace": "ns", "labels": {"service": "a"}}, "spec": {"nodeName": "prod1"}},
+		{"metadata": {"name": "a-3", "uid": "3", "namespace": "ns", "labels": {"service": "a"}}, "spec": {"nodeName": "prod2"}},
+		{"metadata": {"name": "b-1", "uid": "4", "namespace": "ns", "labels": {"service": "b"}}, "spec": {
+			"nodeName": "prod2",
+			"containers": [{"name": "app", "env": [{"name": "A", "value": "1"}, {"name": "A", "value": "2"}]}]
+		}}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := fake.NewServer()
+	defer s.Close()
+
+	importer := NewPodImporter(s.Client(), 0, 2)
+
+	if err = ImportPods(context.Background(), importer, filePath, ImportOptions{}); err != nil {
+		t.Fatalf("ImportPods() error = %v", err)
+	}
+
+	pods := s.Pods()
+	if len(pods) != 3 || importer.ImportedPodsCount() != 3 {
+		t.Fatalf("ImportPods() imported %d pods, want 3", len(pods))
+	}
+
+	for _, pod := range pods {
+		metadata := pod["metadata"].(fake.Object)
+		if _, ok := metadata["namespace"]; ok {
+			t.Errorf("pod %s namespace is not removed", metadata["name"])
+		}
+	}
+
+	env := pods[2]["spec"].(fake.Object)["containers"].([]interface{})[0].(fake.Object)["env"].([]interface{})
+	if len(env) != 1 {
+		t.Errorf("pod b-1 env is not deduplicated: %v", env)
+	}
+}
+
+func TestImportPodsConcurrent(t *testing.T) {
+	var items []string
+	for svc := 0; svc < 10; svc++ {
+		for replica := 0; replica < 10; replica++ {
+			items = append(items, fmt.Sprintf(
+				`{"metadata": {"name": "svc%d-%d", "labels": {"service": "svc%d"}}, "spec": {}}`, svc, replica, svc,
+			))
+		}
+	}
+
+	filePath := filepath.Join(t.TempDir(), "pods.json")
+	if err := ioutil.WriteFile(filePath, []byte(`{"items": [`+strings.Join(items, ",")+`]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := fake.NewServer()
+	defer s.Close()
+
+	importer := NewPodImporter(s.Client(), 25, 3)
+
+	err := ImportPods(context.Background(), importer, filePath, ImportOptions{Workers: 8, RateLimit: 1000, Burst: 8})
+	if err != nil {
+		t.Fatalf("ImportPods() error = %v", err)
+	}
+
+	if got := len(s.Pods()); got != 25 || importer.ImportedPodsCount() != 25 {
+		t.Fatalf("ImportPods() imported %d pods (counted %d), want 25", got, importer.ImportedPodsCount())
+	}
+
+	perSvc := map[string]int{}
+	for _, pod := range s.Pods() {
+		perSvc[pod["metadata"].(fake.Object)["labels"].(fake.Object)["service"].(string)]++
+	}
+	for svc, count := range perSvc {
+		if count > 3 {
+			t.Errorf("service %s got %d pods, want at most 3", svc, count)
+		}
+	}
+}
+
+func TestImportPodsErrors(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "pods.json")
+	err := ioutil.WriteFile(filePath, []byte(`{"items": [
+		{"metadata": {"name": "pod1"}}, {"metadata": {"name": "pod2"}}, {"metadata": {"name": "pod3"}}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := fake.NewServer()
+	defer s.Close()
+
+	s.InjectFault(fake.Fault{Path: "/api/v1/pods", Times: 3, Status: http.StatusBadRequest})
+
+	err = ImportPods(context.Background(), NewPodImporter(s.Client(), 0, 10), filePath, ImportOptions{Workers: 3})
+
+	var errs ImportErrors
+	if !errors.As(err, &errs) || len(errs) == 0 {
+		t.Fatalf("ImportPods() error = %v, want ImportErrors", err)
+	}
+}
+
+func TestDeduplicateEnvVars(t *testing.T) {
+	root, err := insaneJSON.DecodeString(`{"spec": {"containers": [
+		{"name": "app", "env": [{"name": "A", "value": "1"}, {"name": "B", "value": "1"}, {"name": "A", "value": "2"}, {"name": "A", "value": "3"}, {"name": "B", "value": "2"}]},
+		{"name": "sidecar", "env": [{"name": "A", "value": "4"}]},
+		{"name": "no-env"}
+	]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer insaneJSON.Release(root)
+
+	(&PodImporter{}).deduplicateEnvVars(root.Node)
+
+	want := `{"spec":{"containers":[` +
+		`{"name":"app","env":[{"name":"A","value":"1"},{"name":"B","value":"1"}]},` +
+		`{"name":"sidecar","env":[{"name":"A","value":"4"}]},` +
+		`{"name":"no-env"}]}}`
+	if got := root.EncodeToString(); got != want {
+		t.Errorf("deduplicateEnvVars() got %s, want %s", got, want)
+	}
+}
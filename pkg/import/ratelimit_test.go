@@ -0,0 +1,35 @@
+package _import
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(100, 5)
+
+	started := time.Now()
+	for i := 0; i < 15; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 5 events pass with burst, 10 more take 100ms at 100 events per second
+	if elapsed := time.Since(started); elapsed < 90*time.Millisecond {
+		t.Errorf("rateLimiter allowed 15 events in %s", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := newRateLimiter(0.1, 1)
+	_ = l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Wait(ctx); err == nil {
+		t.Error("rateLimiter.Wait() expected context error")
+	}
+}
@@ -0,0 +1,112 @@
+package _import
+
+import (
+	"log"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// PodRequests returns effective pod requests the way NodeResourcesFit plugin computes them and naive sum of containers requests.
+// Effective requests are max(sum of containers and sidecars, max of init containers with sidecars started before them) plus overhead,
+// sidecars are init containers with restartPolicy Always which keep running next to containers.
+// Amounts are parsed by ParseResources.
+func PodRequests(pod *insaneJSON.Node) (effective, naive map[string]int64) {
+	naive = map[string]int64{}
+	for _, c := range pod.Dig("spec", "containers").AsArray() {
+		addResources(naive, ParseResources(c.Dig("resources", "requests")))
+	}
+
+	effective = map[string]int64{}
+	addResources(effective, naive)
+
+	sidecars, initMax := map[string]int64{}, map[string]int64{}
+	for _, c := range pod.Dig("spec", "initContainers").AsArray() {
+		r := ParseResources(c.Dig("resources", "requests"))
+
+		if c.Dig("restartPolicy").AsString() == "Always" {
+			addResources(effective, r)
+			addResources(sidecars, r)
+			maxResources(initMax, sidecars)
+			continue
+		}
+
+		addResources(r, sidecars)
+		maxResources(initMax, r)
+	}
+
+	maxResources(effective, initMax)
+	addResources(effective, ParseResources(pod.Dig("spec", "overhead")))
+
+	return effective, naive
+}
+
+// ParseResources parses resource list of requests or allocatable: cpu to millicores, other resources
+// to integer units (bytes, pods, devices), unparsable quantities are logged and zero
+func ParseResources(list *insaneJSON.Node) map[string]int64 {
+	out := map[string]int64{}
+
+	for _, field := range list.AsFields() {
+		name, v := field.AsString(), field.AsFieldValue().AsString()
+
+		q, err := quantityValue(name, v)
+		if err != nil {
+			log.Printf("Can't parse %s quantity: %s, error: %s\n", name, v, err)
+		}
+
+		out[copyString(name)] = q
+	}
+
+	return out
+}
+
+func addResources(dst, src map[string]int64) {
+	for name, v := range src {
+		dst[name] += v
+	}
+}
+
+func maxResources(dst, src map[string]int64) {
+	for name, v := range src {
+		if v > dst[name] {
+			dst[name] = v
+		}
+	}
+}
+
+// quantityValue parses quantity of resource: cpu to millicores, other resources to integer units (bytes, pods, devices)
+func quantityValue(name, v string) (int64, error) {
+	if name == "cpu" {
+		return milliCPU(v)
+	}
+
+	return memBytes(v)
+}
+
+// milliCPU parses cpu quantity to millicores, fractions of millicore are rounded up, empty quantity is zero
+func milliCPU(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.MilliValue(), nil
+}
+
+// memBytes parses memory quantity to bytes, fractions of byte are rounded up, empty quantity is zero
+func memBytes(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Value(), nil
+}
@@ -0,0 +1,129 @@
+package _import
+
+import "testing"
+
+func Test_milliCPU(t *testing.T) {
+	tests := []struct {
+		val  string
+		want int64
+	}{
+		{
+			"500m",
+			500,
+		},
+		{
+			"3000m",
+			3000,
+		},
+		{
+			"1m",
+			1,
+		},
+		{
+			"5",
+			5000,
+		},
+		{
+			"2.5",
+			2500,
+		},
+		{
+			"0.1m",
+			1,
+		},
+		{
+			"1e3",
+			1000000,
+		},
+		{
+			"",
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			got, err := milliCPU(tt.val)
+			if err != nil {
+				t.Errorf("milliCPU() error = %v", err)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("milliCPU() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_memBytes(t *testing.T) {
+	tests := []struct {
+		val  string
+		want int64
+	}{
+		{
+			"512M",
+			512000000,
+		},
+		{
+			"512Mi",
+			512 << 20,
+		},
+		{
+			"5G",
+			5000000000,
+		},
+		{
+			"1Gi",
+			1 << 30,
+		},
+		{
+			"1Ti",
+			1 << 40,
+		},
+		{
+			"2Pi",
+			2 << 50,
+		},
+		{
+			"1E",
+			1000000000000000000,
+		},
+		{
+			"128974848",
+			128974848,
+		},
+		{
+			"129e6",
+			129000000,
+		},
+		{
+			"100Ki",
+			102400,
+		},
+		{
+			"",
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			got, err := memBytes(tt.val)
+			if err != nil {
+				t.Errorf("memBytes() error = %v", err)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("memBytes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_memBytesInvalid(t *testing.T) {
+	for _, v := range []string{"1GB", "lots", "1.5.1"} {
+		if _, err := memBytes(v); err == nil {
+			t.Errorf("memBytes(%q) error = nil, want error", v)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package _import
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,13 +10,14 @@ import (
 )
 
 // ResetExportState fully resets kube-scheduler-simulator state
-func ResetExportState(c *client.HTTPClient) error {
-	resp, err := c.Reset()
+func ResetExportState(ctx context.Context, c client.SimulatorClient) error {
+	resp, err := c.Reset(ctx)
 	if err != nil {
 		return err
 	}
 	b, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode >= http.StatusMultipleChoices {
+		_ = resp.Body.Close()
 		return fmt.Errorf("got scheduler response status: %d: %s", resp.StatusCode, b)
 	}
 
@@ -0,0 +1,82 @@
+package _import
+
+import (
+	"fmt"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// QuantityRange is an inclusive range of resource quantities, e.g. min: 100m, max: "4", empty bound is not checked
+type QuantityRange struct {
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+}
+
+// rule returns a reason to skip the item or empty string if item passes the rule
+type rule struct {
+	name  string
+	check func(item *insaneJSON.Node) string
+}
+
+// rules is a composed filter, item passes it if it passes all rules
+type rules []rule
+
+func (rs *rules) add(name string, check func(item *insaneJSON.Node) string) {
+	*rs = append(*rs, rule{name: name, check: check})
+}
+
+// addRange adds rule checking item quantity against range, rule is not added if range is empty
+func (rs *rules) addRange(name, what string, r QuantityRange, quantity func(item *insaneJSON.Node) resource.Quantity) error {
+	var bounds [2]*resource.Quantity
+	for i, v := range []string{r.Min, r.Max} {
+		if v == "" {
+			continue
+		}
+
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		bounds[i] = &q
+	}
+
+	min, max := bounds[0], bounds[1]
+	if min == nil && max == nil {
+		return nil
+	}
+
+	rs.add(name, func(item *insaneJSON.Node) string {
+		q := quantity(item)
+
+		if min != nil && q.Cmp(*min) < 0 {
+			return fmt.Sprintf("%s %s < %s", what, q.String(), min.String())
+		}
+		if max != nil && q.Cmp(*max) > 0 {
+			return fmt.Sprintf("%s %s > %s", what, q.String(), max.String())
+		}
+		return ""
+	})
+
+	return nil
+}
+
+// skipReason returns name of the first rule the item does not pass and the reason, empty name if item passes all rules
+func (rs rules) skipReason(item *insaneJSON.Node) (string, string) {
+	for _, r := range rs {
+		if reason := r.check(item); reason != "" {
+			return r.name, reason
+		}
+	}
+
+	return "", ""
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
@@ -0,0 +1,113 @@
+package _import
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	insaneJSON "github.com/vitkovskii/insane-json"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// stratum is a group of nodes sharing stratum key in input order
+type stratum struct {
+	key   string
+	names []string
+	quota int
+	rem   int
+}
+
+// sample reads all nodes from source and chooses import limit nodes keeping proportion of every stratum,
+// chosen node names are kept in i.sampled, all read nodes are returned for import
+func (i *NodeImporter) sample(src source.Source) ([][]byte, error) {
+	var (
+		items   [][]byte
+		strata  []*stratum
+		byKey   = map[string]*stratum{}
+		matched int
+	)
+
+	for {
+		b, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, b)
+
+		root, err := insaneJSON.DecodeBytes(b)
+		if err != nil {
+			return nil, fmt.Errorf("node #%d: %w", len(items)-1, err)
+		}
+
+		if rule, _ := i.filter.rules.skipReason(root.Node); rule == "" {
+			key := i.filter.stratum(root.Node)
+			s, ok := byKey[key]
+			if !ok {
+				s = &stratum{key: copyString(key)}
+				byKey[s.key] = s
+				strata = append(strata, s)
+			}
+			s.names = append(s.names, copyString(root.Dig("metadata", "name").AsString()))
+			matched++
+		}
+
+		insaneJSON.Release(root)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.sampled = map[string]bool{}
+	for _, s := range allocate(strata, i.importNodesLimit, matched) {
+		for _, idx := range spread(len(s.names), s.quota) {
+			i.sampled[s.names[idx]] = true
+		}
+	}
+
+	return items, nil
+}
+
+// allocate splits limit between strata proportionally to their sizes with the largest remainder method,
+// ties are broken by stratum size and key so the sample is reproducible
+func allocate(strata []*stratum, limit, total int) []*stratum {
+	if limit > total {
+		limit = total
+	}
+
+	left := limit
+	for _, s := range strata {
+		s.quota = limit * len(s.names) / total
+		s.rem = limit * len(s.names) % total
+		left -= s.quota
+	}
+
+	byRem := append([]*stratum(nil), strata...)
+	sort.SliceStable(byRem, func(a, b int) bool {
+		if byRem[a].rem != byRem[b].rem {
+			return byRem[a].rem > byRem[b].rem
+		}
+		if len(byRem[a].names) != len(byRem[b].names) {
+			return len(byRem[a].names) > len(byRem[b].names)
+		}
+		return byRem[a].key < byRem[b].key
+	})
+	for k := 0; k < left; k++ {
+		byRem[k].quota++
+	}
+
+	return strata
+}
+
+// spread returns q indexes evenly spaced over n items, so a sample does not come from the head of the input only
+func spread(n, q int) []int {
+	out := make([]int, 0, q)
+	for k := 0; k < q; k++ {
+		out = append(out, (2*k+1)*n/(2*q))
+	}
+
+	return out
+}
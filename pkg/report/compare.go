@@ -0,0 +1,241 @@
+package report
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+)
+
+// targetPower is a power of test used to estimate required iterations count
+const targetPower = 0.8
+
+// maxExactMannWhitneyN is a max sample size for which exact Mann-Whitney U distribution is used
+const maxExactMannWhitneyN = 20
+
+// Comparison is a significance test of metric difference between two configs
+type Comparison struct {
+	Metric string
+	A, B   *Summary
+
+	// Welch's t-test
+	WelchT  float64
+	WelchDF float64
+	WelchP  float64
+
+	// Mann-Whitney U test, exact for small samples without ties, otherwise normal approximation with tie correction
+	MannWhitneyU     float64
+	MannWhitneyP     float64
+	MannWhitneyExact bool
+
+	// CohenD is a standardized mean difference (A - B) / pooled stddev
+	CohenD float64
+	// RankBiserial is a Mann-Whitney effect size in [-1, 1]
+	RankBiserial float64
+
+	// RequiredN is iterations count per config required to detect observed effect
+	// with alpha significance and 0.8 power, 0 if it can't be estimated
+	RequiredN int
+	// Underpowered is true when there are less iterations than RequiredN
+	Underpowered bool
+}
+
+// Compare runs significance tests between records of two configs on every metric
+func Compare(a, b []*result.Record, alpha float64) []*Comparison {
+	out := make([]*Comparison, 0, len(Metrics))
+
+	for _, m := range Metrics {
+		out = append(out, CompareValues(m.Name, m.Values(a), m.Values(b), alpha))
+	}
+
+	return out
+}
+
+// CompareValues runs significance tests between two samples
+func CompareValues(metric string, a, b []float64, alpha float64) *Comparison {
+	c := &Comparison{
+		Metric: metric,
+		A:      Summarize(a),
+		B:      Summarize(b),
+	}
+
+	c.WelchT, c.WelchDF, c.WelchP = WelchTTest(a, b)
+	c.MannWhitneyU, c.MannWhitneyP, c.MannWhitneyExact = MannWhitneyU(a, b)
+	c.CohenD = cohenD(a, b)
+	if len(a) > 0 && len(b) > 0 {
+		c.RankBiserial = 2*c.MannWhitneyU/float64(len(a)*len(b)) - 1
+	}
+
+	c.RequiredN = requiredN(c.CohenD, alpha)
+	c.Underpowered = c.RequiredN > 0 && (c.RequiredN > len(a) || c.RequiredN > len(b))
+
+	return c
+}
+
+// WelchTTest runs two-sided Welch's t-test for samples with unequal variances
+func WelchTTest(a, b []float64) (t, df, p float64) {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+
+	meanA, varA := stat.MeanVariance(a, nil)
+	meanB, varB := stat.MeanVariance(b, nil)
+	seA, seB := varA/float64(len(a)), varB/float64(len(b))
+
+	if seA+seB == 0 {
+		if meanA == meanB {
+			return 0, math.NaN(), 1
+		}
+		return math.Copysign(math.Inf(1), meanA-meanB), math.NaN(), 0
+	}
+
+	t = (meanA - meanB) / math.Sqrt(seA+seB)
+	df = (seA + seB) * (seA + seB) / (seA*seA/float64(len(a)-1) + seB*seB/float64(len(b)-1))
+
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}
+	p = 2 * dist.Survival(math.Abs(t))
+
+	return t, df, p
+}
+
+// MannWhitneyU runs two-sided Mann-Whitney U test, U is calculated for sample a.
+// P-value is exact when both samples have at most 20 values and there are no ties,
+// otherwise normal approximation is used
+func MannWhitneyU(a, b []float64) (u, p float64, exact bool) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return math.NaN(), math.NaN(), false
+	}
+
+	type value struct {
+		v     float64
+		fromA bool
+	}
+
+	all := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, value{v, true})
+	}
+	for _, v := range b {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	var rankSumA, tieCorrection float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+
+		// tied values get average rank
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+
+		ties := float64(j - i)
+		tieCorrection += ties*ties*ties - ties
+		i = j
+	}
+
+	u = rankSumA - n1*(n1+1)/2
+
+	if tieCorrection == 0 && len(a) <= maxExactMannWhitneyN && len(b) <= maxExactMannWhitneyN {
+		return u, mannWhitneyExactP(len(a), len(b), int(u)), true
+	}
+
+	n := n1 + n2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieCorrection/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1, false
+	}
+
+	// continuity correction
+	z := (math.Abs(u-n1*n2/2) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+
+	p = 2 * distuv.UnitNormal.Survival(z)
+
+	return u, p, false
+}
+
+// mannWhitneyExactP returns two-sided p-value of U for samples of sizes n1 and n2 without ties
+func mannWhitneyExactP(n1, n2, u int) float64 {
+	counts := mannWhitneyCounts(n1, n2)
+
+	var total, lower float64
+	for k, c := range counts {
+		total += c
+		if k <= u {
+			lower += c
+		}
+	}
+
+	// distribution is symmetric, so the upper tail of u is the lower tail of n1*n2 - u
+	upper := 0.0
+	for k := 0; k <= n1*n2-u; k++ {
+		upper += counts[k]
+	}
+
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
+
+// mannWhitneyCounts returns numbers of samples orderings for every U value from 0 to n1*n2,
+// they are coefficients of Gaussian binomial polynomial [n1+n2 choose n1] = prod (1 - q^(n2+i)) / (1 - q^i)
+func mannWhitneyCounts(n1, n2 int) []float64 {
+	counts := make([]float64, n1*n2+1)
+	counts[0] = 1
+
+	for i := 1; i <= n1; i++ {
+		// multiply by 1 - q^(n2+i), terms above n1*n2 are always zero in the result and are dropped
+		for k := len(counts) - 1; k >= n2+i; k-- {
+			counts[k] -= counts[k-n2-i]
+		}
+		// divide by 1 - q^i
+		for k := i; k < len(counts); k++ {
+			counts[k] += counts[k-i]
+		}
+	}
+
+	return counts
+}
+
+// cohenD returns standardized mean difference with pooled standard deviation
+func cohenD(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+
+	meanA, varA := stat.MeanVariance(a, nil)
+	meanB, varB := stat.MeanVariance(b, nil)
+
+	pooled := math.Sqrt((float64(len(a)-1)*varA + float64(len(b)-1)*varB) / float64(len(a)+len(b)-2))
+	if pooled == 0 {
+		if meanA == meanB {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), meanA-meanB)
+	}
+
+	return (meanA - meanB) / pooled
+}
+
+// requiredN estimates per group sample size for two-sided test detecting effect size d
+func requiredN(d, alpha float64) int {
+	if math.IsNaN(d) || math.IsInf(d, 0) || d == 0 {
+		return 0
+	}
+
+	zAlpha := distuv.UnitNormal.Quantile(1 - alpha/2)
+	zPower := distuv.UnitNormal.Quantile(targetPower)
+
+	return int(math.Ceil(2 * math.Pow((zAlpha+zPower)/d, 2)))
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// PrintComparison renders significance tests table and warns about underpowered metrics
+func PrintComparison(w io.Writer, configA, configB string, comparisons []*Comparison, alpha float64) {
+	fmt.Fprintf(w, "A: %s\nB: %s\n", configA, configB)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Metric", "Mean A", "Mean B", "Welch t", "Welch p", "MWU U", "MWU p", "Cohen d", "Rank-biserial", "Significant"})
+
+	approximated := false
+	for _, c := range comparisons {
+		significant := "no"
+		if c.WelchP < alpha && c.MannWhitneyP < alpha {
+			significant = "yes"
+		} else if c.WelchP < alpha || c.MannWhitneyP < alpha {
+			significant = "one test"
+		}
+
+		mannWhitneyP := fmt.Sprintf("%.4f", c.MannWhitneyP)
+		if !c.MannWhitneyExact {
+			mannWhitneyP += " ~"
+			approximated = true
+		}
+
+		table.Append([]string{
+			c.Metric,
+			fmt.Sprintf("%.3f", c.A.Mean),
+			fmt.Sprintf("%.3f", c.B.Mean),
+			fmt.Sprintf("%.3f", c.WelchT),
+			fmt.Sprintf("%.4f", c.WelchP),
+			fmt.Sprintf("%.1f", c.MannWhitneyU),
+			mannWhitneyP,
+			fmt.Sprintf("%.3f", c.CohenD),
+			fmt.Sprintf("%.3f", c.RankBiserial),
+			significant,
+		})
+	}
+
+	table.Render()
+
+	if approximated {
+		fmt.Fprintln(w, "~ MWU p is a normal approximation: there are ties or more than 20 iterations per config")
+	}
+
+	for _, c := range comparisons {
+		if c.Underpowered {
+			fmt.Fprintf(
+				w,
+				"WARNING: %s: %d/%d iterations are too few to detect observed effect (d=%.2f), need ~%d per config\n",
+				c.Metric, c.A.N, c.B.N, c.CohenD, c.RequiredN,
+			)
+		}
+	}
+}
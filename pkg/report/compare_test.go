@@ -0,0 +1,83 @@
+package report
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWelchTTest(t *testing.T) {
+	// reference values from scipy.stats.ttest_ind(a, b, equal_var=False)
+	a := []float64{27.5, 21.0, 19.0, 23.6, 17.0, 17.9, 16.9, 20.1, 21.9, 22.6, 23.1, 19.6, 19.0, 21.7, 21.4}
+	b := []float64{27.1, 22.0, 20.8, 23.4, 23.4, 23.5, 25.8, 22.0, 24.8, 20.2, 21.9, 22.1, 22.9, 20.5, 24.4}
+
+	tt, df, p := WelchTTest(a, b)
+
+	if math.Abs(tt-(-2.46)) > 0.01 || math.Abs(df-24.99) > 0.01 || math.Abs(p-0.021) > 0.001 {
+		t.Errorf("WelchTTest() got t=%v df=%v p=%v", tt, df, p)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	// reference values from scipy.stats.mannwhitneyu(a, b, method="exact") and method="asymptotic" for ties
+	tests := []struct {
+		name  string
+		a, b  []float64
+		u, p  float64
+		exact bool
+	}{
+		{"exact", []float64{19, 22, 16, 29, 24}, []float64{20, 11, 17, 12}, 17, 0.1111, true},
+		{"exact separated", []float64{1, 2, 3}, []float64{4, 5, 6}, 0, 0.1, true},
+		{"ties", []float64{1, 2, 2, 3}, []float64{2, 4, 5, 6}, 2, 0.1038, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, p, exact := MannWhitneyU(tt.a, tt.b)
+
+			if u != tt.u || math.Abs(p-tt.p) > 0.001 || exact != tt.exact {
+				t.Errorf("MannWhitneyU() got u=%v p=%v exact=%v, want u=%v p=%v exact=%v", u, p, exact, tt.u, tt.p, tt.exact)
+			}
+		})
+	}
+}
+
+func Test_mannWhitneyCounts(t *testing.T) {
+	for _, n := range [][2]int{{1, 1}, {3, 4}, {8, 5}, {20, 20}} {
+		counts := mannWhitneyCounts(n[0], n[1])
+
+		// every ordering of n1+n2 values has exactly one U
+		total := 0.0
+		for _, c := range counts {
+			total += c
+		}
+		if want := binomial(n[0]+n[1], n[0]); total != want {
+			t.Errorf("mannWhitneyCounts(%d, %d) total = %v, want %v", n[0], n[1], total, want)
+		}
+		if counts[0] != 1 || counts[len(counts)-1] != 1 {
+			t.Errorf("mannWhitneyCounts(%d, %d) = %v, want a single ordering for extreme U", n[0], n[1], counts)
+		}
+	}
+}
+
+func binomial(n, k int) float64 {
+	out := 1.0
+	for i := 1; i <= k; i++ {
+		out = out * float64(n-k+i) / float64(i)
+	}
+
+	return math.Round(out)
+}
+
+func TestCompareValuesUnderpowered(t *testing.T) {
+	c := CompareValues("m", []float64{1, 2, 3}, []float64{1.5, 2.5, 3.5}, 0.05)
+
+	if !c.Underpowered || c.RequiredN <= 3 {
+		t.Errorf("CompareValues() expected underpowered comparison, got required n = %d", c.RequiredN)
+	}
+
+	c = CompareValues("m", []float64{1, 1.1, 0.9, 1}, []float64{5, 5.1, 4.9, 5}, 0.05)
+
+	if c.Underpowered || c.WelchP > 0.001 {
+		t.Errorf("CompareValues() expected significant difference, got p = %v, required n = %d", c.WelchP, c.RequiredN)
+	}
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+)
+
+// Metric is an iteration result value compared across configs, lower value is better
+type Metric struct {
+	Name  string
+	Value func(r *result.Record) float64
+}
+
+// Metrics are all metrics included in reports
+var Metrics = []Metric{
+	{"cpu_imbalance", func(r *result.Record) float64 { return r.CPU.Imbalance }},
+	{"cpu_stddev", func(r *result.Record) float64 { return r.CPU.StdDev }},
+	{"mem_imbalance_gib", func(r *result.Record) float64 { return r.Mem.Imbalance }},
+	{"mem_stddev_gib", func(r *result.Record) float64 { return r.Mem.StdDev }},
+	{"cpu_util_imbalance", func(r *result.Record) float64 { return r.Utilization["cpu"].Imbalance }},
+	{"cpu_util_cv", func(r *result.Record) float64 { return r.Utilization["cpu"].CV }},
+	{"cpu_util_gini", func(r *result.Record) float64 { return r.Utilization["cpu"].Gini }},
+	{"mem_util_imbalance", func(r *result.Record) float64 { return r.Utilization["memory"].Imbalance }},
+	{"mem_util_cv", func(r *result.Record) float64 { return r.Utilization["memory"].CV }},
+	{"mem_util_gini", func(r *result.Record) float64 { return r.Utilization["memory"].Gini }},
+	{"stranded_cpu", func(r *result.Record) float64 { return r.Fragmentation.StrandedCPU }},
+	{"stranded_mem_gib", func(r *result.Record) float64 { return r.Fragmentation.StrandedMemGb }},
+	{"unscheduled_pods", func(r *result.Record) float64 { return float64(len(r.UnscheduledPods)) }},
+}
+
+// GroupByConfig groups records by config preserving configs order of appearance
+func GroupByConfig(records []*result.Record) (configs []string, groups map[string][]*result.Record) {
+	groups = map[string][]*result.Record{}
+
+	for _, r := range records {
+		if _, ok := groups[r.Config]; !ok {
+			configs = append(configs, r.Config)
+		}
+		groups[r.Config] = append(groups[r.Config], r)
+	}
+
+	return configs, groups
+}
+
+// FilterConfig returns records of config matched by path or file name with or without extension,
+// file name matching several configs is an error
+func FilterConfig(records []*result.Record, config string) (string, []*result.Record, error) {
+	configs, groups := GroupByConfig(records)
+	if out, ok := groups[config]; ok {
+		return config, out, nil
+	}
+
+	var matched []string
+	for _, c := range configs {
+		if filepath.Base(c) == config || result.ConfigName(c) == config {
+			matched = append(matched, c)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return "", nil, nil
+	case 1:
+		return matched[0], groups[matched[0]], nil
+	}
+
+	return "", nil, fmt.Errorf("config %s is ambiguous, it matches %s", config, strings.Join(matched, ", "))
+}
+
+// Values extracts metric values from records
+func (m Metric) Values(records []*result.Record) []float64 {
+	out := make([]float64, 0, len(records))
+	for _, r := range records {
+		out = append(out, m.Value(r))
+	}
+
+	return out
+}
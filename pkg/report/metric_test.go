@@ -0,0 +1,30 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+)
+
+func TestFilterConfig(t *testing.T) {
+	records := []*result.Record{
+		{Config: "a/scheduler.yaml", Iteration: 1},
+		{Config: "b/scheduler.yaml", Iteration: 1},
+		{Config: "b/scheduler.yaml", Iteration: 2},
+		{Config: "b/default.json", Iteration: 1},
+	}
+
+	config, out, err := FilterConfig(records, "b/scheduler.yaml")
+	if err != nil || config != "b/scheduler.yaml" || len(out) != 2 {
+		t.Errorf("FilterConfig() by path = %s, %d records, %v", config, len(out), err)
+	}
+
+	config, out, err = FilterConfig(records, "default")
+	if err != nil || config != "b/default.json" || len(out) != 1 {
+		t.Errorf("FilterConfig() by name = %s, %d records, %v", config, len(out), err)
+	}
+
+	if _, _, err = FilterConfig(records, "scheduler"); err == nil {
+		t.Errorf("FilterConfig() expected error for name of several configs")
+	}
+}
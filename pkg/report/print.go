@@ -0,0 +1,44 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Print renders report table per metric, the best config is highlighted
+func Print(w io.Writer, r *Report) {
+	for _, m := range Metrics {
+		fmt.Fprintf(w, "\n%s\n", m.Name)
+
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Rank", "Config", "N", "Mean", "Median", "P95", "95% CI"})
+
+		headColor := tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor}
+		table.SetHeaderColor(headColor, headColor, headColor, headColor, headColor, headColor, headColor)
+
+		for _, cfg := range r.Configs {
+			s := r.Summaries[m.Name][cfg]
+
+			row := []string{
+				fmt.Sprintf("%d", s.Rank),
+				cfg,
+				fmt.Sprintf("%d", s.N),
+				fmt.Sprintf("%.2f", s.Mean),
+				fmt.Sprintf("%.2f", s.Median),
+				fmt.Sprintf("%.2f", s.P95),
+				fmt.Sprintf("[%.2f, %.2f]", s.CILow, s.CIHigh),
+			}
+
+			if s.Rank == 1 {
+				bestColor := tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiGreenColor}
+				table.Rich(row, []tablewriter.Colors{bestColor, bestColor, bestColor, bestColor, bestColor, bestColor, bestColor})
+			} else {
+				table.Append(row)
+			}
+		}
+
+		table.Render()
+	}
+}
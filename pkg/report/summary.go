@@ -0,0 +1,107 @@
+package report
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+)
+
+// confidenceLevel is a confidence level of mean confidence interval
+const confidenceLevel = 0.95
+
+// Summary is a metric distribution over config iterations
+type Summary struct {
+	N      int
+	Mean   float64
+	Median float64
+	P95    float64
+	CILow  float64
+	CIHigh float64
+	// Rank is a config position by mean among all configs, 1 is the best
+	Rank int
+}
+
+// Report is a cross-config comparison of aggregated iteration results
+type Report struct {
+	Configs []string
+	// Summaries are keyed by metric name and config
+	Summaries map[string]map[string]*Summary
+}
+
+// Build aggregates records per config and ranks configs for every metric
+func Build(records []*result.Record) *Report {
+	configs, groups := GroupByConfig(records)
+
+	rep := &Report{
+		Configs:   configs,
+		Summaries: map[string]map[string]*Summary{},
+	}
+
+	for _, m := range Metrics {
+		summaries := map[string]*Summary{}
+		for _, cfg := range configs {
+			summaries[cfg] = Summarize(m.Values(groups[cfg]))
+		}
+
+		rank(configs, summaries)
+
+		rep.Summaries[m.Name] = summaries
+	}
+
+	return rep
+}
+
+// Best returns the best config by metric mean
+func (r *Report) Best(metric string) string {
+	for _, cfg := range r.Configs {
+		if r.Summaries[metric][cfg].Rank == 1 {
+			return cfg
+		}
+	}
+
+	return ""
+}
+
+// Summarize calculates mean, median, p95 and mean confidence interval of values
+func Summarize(values []float64) *Summary {
+	s := &Summary{N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	s.Mean = stat.Mean(sorted, nil)
+	s.Median = stat.Quantile(0.5, stat.Empirical, sorted, nil)
+	s.P95 = stat.Quantile(0.95, stat.Empirical, sorted, nil)
+	s.CILow, s.CIHigh = s.Mean, s.Mean
+
+	if s.N > 1 {
+		t := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(s.N - 1)}
+		margin := t.Quantile(1-(1-confidenceLevel)/2) * stat.StdDev(sorted, nil) / math.Sqrt(float64(s.N))
+
+		s.CILow, s.CIHigh = s.Mean-margin, s.Mean+margin
+	}
+
+	return s
+}
+
+// rank sets configs ranks by metric mean, equal means get equal rank
+func rank(configs []string, summaries map[string]*Summary) {
+	ordered := append([]string{}, configs...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return summaries[ordered[i]].Mean < summaries[ordered[j]].Mean
+	})
+
+	for i, cfg := range ordered {
+		summaries[cfg].Rank = i + 1
+		if i > 0 && summaries[cfg].Mean == summaries[ordered[i-1]].Mean {
+			summaries[cfg].Rank = summaries[ordered[i-1]].Rank
+		}
+	}
+}
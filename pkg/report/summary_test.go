@@ -0,0 +1,56 @@
+package report
+
+import (
+	"math"
+	"testing"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/cluster"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+)
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{4, 1, 3, 2, 5})
+
+	if s.N != 5 || s.Mean != 3 || s.Median != 3 || s.P95 != 5 {
+		t.Errorf("Summarize() got = %+v", s)
+	}
+
+	// t(0.975, 4) = 2.776, sd = 1.5811
+	wantMargin := 2.7764451051977987 * math.Sqrt(2.5) / math.Sqrt(5)
+	if math.Abs(s.CIHigh-s.Mean-wantMargin) > 1e-9 || math.Abs(s.Mean-s.CILow-wantMargin) > 1e-9 {
+		t.Errorf("Summarize() CI got = [%v, %v], want margin %v", s.CILow, s.CIHigh, wantMargin)
+	}
+}
+
+func TestSummarizeSingleValue(t *testing.T) {
+	s := Summarize([]float64{7})
+
+	if s.CILow != 7 || s.CIHigh != 7 {
+		t.Errorf("Summarize() CI got = [%v, %v], want [7, 7]", s.CILow, s.CIHigh)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	records := []*result.Record{
+		{Config: "a", CPU: cluster.Stats{StdDev: 3}},
+		{Config: "a", CPU: cluster.Stats{StdDev: 5}},
+		{Config: "b", CPU: cluster.Stats{StdDev: 1}},
+		{Config: "b", CPU: cluster.Stats{StdDev: 2}},
+		{Config: "c", CPU: cluster.Stats{StdDev: 4}},
+	}
+
+	r := Build(records)
+
+	if len(r.Configs) != 3 || r.Configs[0] != "a" {
+		t.Fatalf("Build() configs = %v", r.Configs)
+	}
+
+	if best := r.Best("cpu_stddev"); best != "b" {
+		t.Errorf("Best() got = %s, want b", best)
+	}
+
+	cpu := r.Summaries["cpu_stddev"]
+	if cpu["a"].Rank != 2 || cpu["c"].Rank != 2 {
+		t.Errorf("Build() ranks got a=%d c=%d, want equal rank 2", cpu["a"].Rank, cpu["c"].Rank)
+	}
+}
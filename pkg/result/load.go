@@ -0,0 +1,41 @@
+package result
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// Load reads all iteration records from results directory in run order
+func Load(dir string) ([]*Record, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(files))
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &Record{}
+		if err = json.Unmarshal(b, r); err != nil {
+			return nil, err
+		}
+
+		records = append(records, r)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].StartedAt.Equal(records[j].StartedAt) {
+			return records[i].Iteration < records[j].Iteration
+		}
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+
+	return records, nil
+}
@@ -0,0 +1,51 @@
+package result
+
+import (
+	"time"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/cluster"
+)
+
+// Record is a result of a single benchmark iteration
+type Record struct {
+	Scenario        string          `json:"scenario"`
+	Config          string          `json:"config"`
+	Iteration       int             `json:"iteration"`
+	Seed            int64           `json:"seed"`
+	StartedAt       time.Time       `json:"startedAt"`
+	NodesCount      int             `json:"nodesCount"`
+	PodsCount       int             `json:"podsCount"`
+	CPU             cluster.Stats   `json:"cpu"`
+	Mem             cluster.Stats   `json:"mem"`
+	Nodes           []*cluster.Node `json:"nodes"`
+	UnscheduledPods []string        `json:"unscheduledPods"`
+	// RequestDiffs are pods which effective requests differ from sum of containers requests
+	RequestDiffs []cluster.PodRequestDiff `json:"requestDiffs,omitempty"`
+	// Resources are allocation stats of every resource of nodes, cpu and memory ones are CPU and Mem
+	Resources map[string]cluster.Stats `json:"resources,omitempty"`
+	// Utilization are allocation stats of every resource normalised by node allocatable
+	Utilization map[string]cluster.Utilization `json:"utilization,omitempty"`
+	// Fragmentation is a stranded capacity and headroom of service pod shapes
+	Fragmentation cluster.Fragmentation `json:"fragmentation"`
+}
+
+// NewRecord builds iteration record from cluster analysis
+func NewRecord(scenario, config string, iteration int, seed int64, startedAt time.Time, a *cluster.Analysis) *Record {
+	return &Record{
+		Scenario:        scenario,
+		Config:          config,
+		Iteration:       iteration,
+		Seed:            seed,
+		StartedAt:       startedAt,
+		NodesCount:      len(a.Nodes),
+		PodsCount:       a.PodsCount,
+		CPU:             a.CPU,
+		Mem:             a.Mem,
+		Nodes:           a.SortedNodes(),
+		UnscheduledPods: a.UnscheduledPods,
+		RequestDiffs:    a.RequestDiffs,
+		Resources:       a.Resources,
+		Utilization:     a.Utilization,
+		Fragmentation:   a.Fragmentation,
+	}
+}
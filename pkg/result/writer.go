@@ -0,0 +1,247 @@
+package result
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const summaryFileName = "summary.csv"
+
+var summaryHeader = []string{
+	"scenario", "config", "iteration", "seed", "started_at", "nodes", "pods", "unscheduled_pods",
+	"cpu_min", "cpu_max", "cpu_imbalance", "cpu_stddev",
+	"mem_min_gib", "mem_max_gib", "mem_imbalance_gib", "mem_stddev_gib",
+	"cpu_util_min", "cpu_util_max", "cpu_util_imbalance", "cpu_util_stddev", "cpu_util_cv", "cpu_util_gini", "cpu_util_jain",
+	"mem_util_min", "mem_util_max", "mem_util_imbalance", "mem_util_stddev", "mem_util_cv", "mem_util_gini", "mem_util_jain",
+	"free_cpu", "stranded_cpu", "stranded_cpu_nodes", "free_mem_gib", "stranded_mem_gib", "stranded_mem_nodes",
+}
+
+var utilizationColumns = []string{"_util_min", "_util_max", "_util_imbalance", "_util_stddev", "_util_cv", "_util_gini", "_util_jain"}
+
+// Writer writes iteration records to results directory: every record as a separate JSON file
+// and a row in flat CSV summary. Summary has min, max, imbalance, stddev and utilization columns of every resource
+// besides cpu and memory found in any record, it is rewritten with new columns when a record brings a new resource
+type Writer struct {
+	dir       string
+	summary   *os.File
+	resources []string
+	// records are written records without nodes and pods lists, summary rows are rebuilt from them
+	records []*Record
+}
+
+// NewWriter creates results directory and CSV summary file in it, existing directory is an error,
+// so results of another run are never overwritten
+func NewWriter(dir string) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(filepath.Join(dir, summaryFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		dir:     dir,
+		summary: f,
+	}, nil
+}
+
+// Dir returns results directory
+func (w *Writer) Dir() string {
+	return w.dir
+}
+
+// Write writes record JSON file and appends record to CSV summary
+func (w *Writer) Write(r *Record) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = writeNewFile(filepath.Join(w.dir, recordFileName(r)), b); err != nil {
+		return err
+	}
+
+	summary := *r
+	summary.Nodes, summary.RequestDiffs, summary.Fragmentation.Services = nil, nil, nil
+	w.records = append(w.records, &summary)
+
+	if w.addResources(r) || len(w.records) == 1 {
+		return w.rewriteSummary()
+	}
+
+	return w.writeRows(w.records[len(w.records)-1:])
+}
+
+// Close closes CSV summary, summary of a run without records has header only
+func (w *Writer) Close() error {
+	if len(w.records) == 0 {
+		if err := w.rewriteSummary(); err != nil {
+			_ = w.summary.Close()
+			return err
+		}
+	}
+
+	return w.summary.Close()
+}
+
+// ConfigName returns scheduler config file name without extension, record files of config are named by it
+func ConfigName(config string) string {
+	return strings.TrimSuffix(filepath.Base(config), filepath.Ext(config))
+}
+
+func recordFileName(r *Record) string {
+	return fmt.Sprintf("%s-%03d.json", ConfigName(r.Config), r.Iteration)
+}
+
+// writeNewFile writes file which must not exist, so records of configs with the same name never overwrite each other
+func writeNewFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// addResources adds extra resources of record to summary columns, reports if there are new ones
+func (w *Writer) addResources(r *Record) bool {
+	known := map[string]bool{"cpu": true, "memory": true}
+	for _, name := range w.resources {
+		known[name] = true
+	}
+
+	added := false
+	for name := range r.Resources {
+		if !known[name] {
+			w.resources = append(w.resources, name)
+			added = true
+		}
+	}
+	sort.Strings(w.resources)
+
+	return added
+}
+
+// rewriteSummary writes summary from scratch: header with columns of all known resources and rows of all records
+func (w *Writer) rewriteSummary() error {
+	if err := w.summary.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.summary.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	header := append([]string{}, summaryHeader...)
+	for _, name := range w.resources {
+		header = append(header, name+"_min", name+"_max", name+"_imbalance", name+"_stddev")
+		for _, column := range utilizationColumns {
+			header = append(header, name+column)
+		}
+	}
+
+	cw := csv.NewWriter(w.summary)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	return w.writeRows(w.records)
+}
+
+func (w *Writer) writeRows(records []*Record) error {
+	cw := csv.NewWriter(w.summary)
+	for _, r := range records {
+		if err := cw.Write(w.summaryRow(r)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// summaryRow returns summary columns of record, resources missing in record are empty
+func (w *Writer) summaryRow(r *Record) []string {
+	row := []string{
+		r.Scenario,
+		r.Config,
+		strconv.Itoa(r.Iteration),
+		strconv.FormatInt(r.Seed, 10),
+		r.StartedAt.Format("2006-01-02T15:04:05Z07:00"),
+		strconv.Itoa(r.NodesCount),
+		strconv.Itoa(r.PodsCount),
+		strconv.Itoa(len(r.UnscheduledPods)),
+		formatFloat(r.CPU.Min),
+		formatFloat(r.CPU.Max),
+		formatFloat(r.CPU.Imbalance),
+		formatFloat(r.CPU.StdDev),
+		formatFloat(r.Mem.Min),
+		formatFloat(r.Mem.Max),
+		formatFloat(r.Mem.Imbalance),
+		formatFloat(r.Mem.StdDev),
+	}
+
+	row = append(row, utilizationRow(r, "cpu")...)
+	row = append(row, utilizationRow(r, "memory")...)
+	row = append(row,
+		formatFloat(r.Fragmentation.FreeCPU),
+		formatFloat(r.Fragmentation.StrandedCPU),
+		strconv.Itoa(r.Fragmentation.StrandedCPUNodes),
+		formatFloat(r.Fragmentation.FreeMemGb),
+		formatFloat(r.Fragmentation.StrandedMemGb),
+		strconv.Itoa(r.Fragmentation.StrandedMemNodes),
+	)
+
+	for _, name := range w.resources {
+		if s, ok := r.Resources[name]; ok {
+			row = append(row, formatFloat(s.Min), formatFloat(s.Max), formatFloat(s.Imbalance), formatFloat(s.StdDev))
+		} else {
+			row = append(row, "", "", "", "")
+		}
+		row = append(row, utilizationRow(r, name)...)
+	}
+
+	return row
+}
+
+// utilizationRow returns utilization columns of resource, empty if record has no utilization of resource
+func utilizationRow(r *Record, name string) []string {
+	u, ok := r.Utilization[name]
+	if !ok {
+		return make([]string, len(utilizationColumns))
+	}
+
+	return []string{
+		formatFloat(u.Min),
+		formatFloat(u.Max),
+		formatFloat(u.Imbalance),
+		formatFloat(u.StdDev),
+		formatFloat(u.CV),
+		formatFloat(u.Gini),
+		formatFloat(u.Jain),
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,160 @@
+package result
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/cluster"
+)
+
+func TestWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+
+	w, err := NewWriter(dir)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	r := &Record{
+		Scenario:  "test",
+		Config:    "./testdata/config_default.json",
+		Iteration: 2,
+		Seed:      3,
+		StartedAt: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC),
+		CPU:       cluster.Stats{Min: 1, Max: 3, Imbalance: 2, StdDev: 1},
+		Nodes:     []*cluster.Node{{Name: "node1", AllocatedCores: 1}},
+	}
+
+	if err = w.Write(r); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err = w.Write(&Record{Config: "./other/config_default.yaml", Iteration: 2}); err == nil {
+		t.Errorf("Write() expected error for record of config with the same name")
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "config_default-002.json"))
+	if err != nil {
+		t.Fatalf("record file not written: %v", err)
+	}
+
+	got := &Record{}
+	if err = json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CPU != r.CPU || len(got.Nodes) != 1 || got.Nodes[0].Name != "node1" {
+		t.Errorf("record got = %+v, want %+v", got, r)
+	}
+
+	f, err := os.Open(filepath.Join(dir, summaryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rows[1]) != len(summaryHeader) {
+		t.Fatalf("summary got %d rows: %v", len(rows), rows)
+	}
+	if rows[1][2] != "2" || rows[1][10] != "2" {
+		t.Errorf("summary row got = %v", rows[1])
+	}
+}
+
+func TestWriterExistingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results", "run")
+
+	w, err := NewWriter(dir)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewWriter(dir); !os.IsExist(err) {
+		t.Errorf("NewWriter() error = %v, want already exists error", err)
+	}
+}
+
+func TestWriterResources(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+
+	w, err := NewWriter(dir)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	for i, resources := range []map[string]cluster.Stats{
+		{"cpu": {Max: 1}, "pods": {Min: 10, Max: 30, Imbalance: 20, StdDev: 10}, "nvidia.com/gpu": {Max: 2}},
+		{"cpu": {Max: 1}, "pods": {Max: 5}, "ephemeral-storage": {Max: 100}},
+	} {
+		utilization := map[string]cluster.Utilization{"pods": {Stats: cluster.Stats{Min: 0.5}}}
+		if err = w.Write(&Record{Config: "config.json", Iteration: i, Resources: resources, Utilization: utilization}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, summaryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := map[string]int{}
+	for i, column := range rows[0] {
+		header[column] = i
+	}
+
+	if len(rows) != 3 || len(rows[0]) != len(summaryHeader)+33 {
+		t.Fatalf("summary got %d rows, header = %v", len(rows), rows[0])
+	}
+	for _, row := range rows[1:] {
+		if len(row) != len(rows[0]) {
+			t.Fatalf("summary row %v does not match header", row)
+		}
+	}
+
+	// ephemeral-storage first appears in the second record and is added to the header of the whole summary
+	cells := []struct {
+		row    int
+		column string
+		want   string
+	}{
+		{1, "nvidia.com/gpu_max", "2"},
+		{1, "pods_imbalance", "20"},
+		{1, "pods_util_min", "0.5"},
+		{1, "ephemeral-storage_max", ""},
+		{2, "nvidia.com/gpu_max", ""},
+		{2, "pods_max", "5"},
+		{2, "ephemeral-storage_max", "100"},
+	}
+	for _, c := range cells {
+		i, ok := header[c.column]
+		if !ok {
+			t.Errorf("summary header has no column %s: %v", c.column, rows[0])
+			continue
+		}
+		if got := rows[c.row][i]; got != c.want {
+			t.Errorf("summary row %d column %s = %q, want %q", c.row, c.column, got, c.want)
+		}
+	}
+}
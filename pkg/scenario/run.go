@@ -0,0 +1,165 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/client"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/cluster"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/report"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+)
+
+// Run executes scenario against kube-scheduler-simulator: for every config it resets the simulator state,
+// imports config, nodes and pods, waits for scheduling to settle and analyses nodes.
+// Every iteration result is written to a new run directory inside scenario results directory,
+// cross-config report is printed at the end of the run. When context is cancelled the run stops
+// and the report is printed for iterations finished so far.
+func Run(ctx context.Context, c client.SimulatorClient, s *Scenario, logEnabled bool) (err error) {
+	log.Printf(
+		"Run scenario %q for nodes: %d, pods: %d, iterations per config: %d...\n",
+		s.Name, s.Nodes.Limit, s.Pods.Limit, s.Iterations,
+	)
+
+	w, err := result.NewWriter(s.runDir(time.Now()))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	records := make([]*result.Record, 0, len(s.Configs)*s.Iterations)
+
+	for i := range s.Configs {
+		for j := 0; j < s.Iterations; j++ {
+			log.Printf("Config: %s, iteration: %d...\n", s.Configs[i], j+1)
+
+			r, err := runIteration(ctx, c, s, s.Configs[i], j+1, logEnabled)
+			if err != nil {
+				if ctx.Err() != nil && len(records) > 0 {
+					log.Printf("Run interrupted, partial results written to %s\n", w.Dir())
+					report.Print(os.Stdout, report.Build(records))
+				}
+				return err
+			}
+
+			if err = w.Write(r); err != nil {
+				return err
+			}
+
+			records = append(records, r)
+		}
+	}
+
+	report.Print(os.Stdout, report.Build(records))
+
+	log.Printf("Results written to %s\n", w.Dir())
+
+	return nil
+}
+
+func runIteration(ctx context.Context, c client.SimulatorClient, s *Scenario, configPath string, iteration int, logEnabled bool) (*result.Record, error) {
+	startedAt := time.Now()
+
+	if err := _import.ResetExportState(ctx, c); err != nil {
+		return nil, err
+	}
+
+	if err := _import.ImportConfig(ctx, c, configPath); err != nil {
+		return nil, err
+	}
+
+	nodeImporter, err := _import.NewNodeImporterWithFilter(c, s.Nodes.Limit, s.Nodes.filter())
+	if err != nil {
+		return nil, err
+	}
+	if err = importNodes(ctx, nodeImporter, s.Nodes, logEnabled); err != nil {
+		return nil, err
+	}
+
+	podImporter, err := _import.NewPodImporterWithFilter(c, s.Pods.Limit, s.Pods.filter())
+	if err != nil {
+		return nil, err
+	}
+	if err = importPods(ctx, podImporter, s.Pods, s.iterationSeed(iteration), logEnabled); err != nil {
+		return nil, err
+	}
+
+	err = cluster.WaitForScheduling(ctx, c, cluster.WaitOptions{
+		ExpectedPods: podImporter.ImportedPodsCount(),
+		Timeout:      s.Wait.Timeout,
+		PollInterval: s.Wait.PollInterval,
+		StablePolls:  s.Wait.StablePolls,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := cluster.Analyze(ctx, c, s.Pods.filter().GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cluster.PrintAnalysis(a); err != nil {
+		return nil, err
+	}
+
+	return result.NewRecord(s.Name, configPath, iteration, s.iterationSeed(iteration), startedAt, a), nil
+}
+
+// importNodes imports nodes from file or generated fleet
+func importNodes(ctx context.Context, importer *_import.NodeImporter, nodes NodeSource, logEnabled bool) error {
+	if nodes.Generate == nil {
+		return _import.ImportNodes(ctx, importer, nodes.File, nodes.Import.options(logEnabled))
+	}
+
+	src, err := nodes.Generate.Source()
+	if err != nil {
+		return err
+	}
+
+	return _import.ImportNodesFrom(ctx, importer, src, nodes.Import.options(logEnabled))
+}
+
+// importPods imports pods from file or generated workload, seed is used if workload seed is not set
+func importPods(ctx context.Context, importer *_import.PodImporter, pods PodSource, seed int64, logEnabled bool) error {
+	if pods.Generate == nil {
+		return _import.ImportPods(ctx, importer, pods.File, pods.Import.options(logEnabled))
+	}
+
+	workload := *pods.Generate
+	if workload.Seed == 0 {
+		workload.Seed = seed
+	}
+
+	src, err := workload.Source()
+	if err != nil {
+		return err
+	}
+
+	return _import.ImportPodsFrom(ctx, importer, src, pods.Import.options(logEnabled))
+}
+
+// runDir returns directory for run results named by scenario and start time with milliseconds,
+// results writer fails if it already exists
+func (s *Scenario) runDir(startedAt time.Time) string {
+	name := s.Name
+	if name == "" {
+		name = "scenario"
+	}
+
+	return filepath.Join(s.Results, fmt.Sprintf("%s-%s", name, startedAt.Format("20060102-150405.000")))
+}
+
+// iterationSeed derives iteration seed from scenario seed, so every iteration is reproducible on its own
+func (s *Scenario) iterationSeed(iteration int) int64 {
+	return s.Seed + int64(iteration)
+}
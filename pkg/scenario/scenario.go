@@ -0,0 +1,276 @@
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/generate"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/result"
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+// Scenario describes a benchmark run: which scheduler configs to model, which nodes and pods to import
+// and how many iterations to run per config
+type Scenario struct {
+	Name       string     `yaml:"name"`
+	Iterations int        `yaml:"iterations"`
+	Seed       int64      `yaml:"seed"`
+	Results    string     `yaml:"results"`
+	Wait       Wait       `yaml:"wait"`
+	Configs    []string   `yaml:"configs"`
+	Nodes      NodeSource `yaml:"nodes"`
+	Pods       PodSource  `yaml:"pods"`
+}
+
+// Wait describes how to wait for scheduling to settle after pods import
+type Wait struct {
+	Timeout      time.Duration `yaml:"timeout"`
+	PollInterval time.Duration `yaml:"pollInterval"`
+	StablePolls  int           `yaml:"stablePolls"`
+}
+
+// NodeSource describes nodes input file and NodeImporter filter parameters
+type NodeSource struct {
+	File    string             `yaml:"file"`
+	Limit   int                `yaml:"limit"`
+	CoresEq int                `yaml:"coresEq"`
+	Filter  _import.NodeFilter `yaml:"filter"`
+	Import  Import             `yaml:",inline"`
+	// Generate imports synthetic fleet instead of file
+	Generate *generate.Fleet `yaml:"generate"`
+}
+
+// filter returns nodes filter, coresEq is a shorthand for filter.coresEq
+func (n NodeSource) filter() _import.NodeFilter {
+	f := n.Filter
+	if f.CoresEq == 0 {
+		f.CoresEq = n.CoresEq
+	}
+
+	return f
+}
+
+// PodSource describes pods input file and PodImporter filter parameters
+type PodSource struct {
+	File          string            `yaml:"file"`
+	Limit         int               `yaml:"limit"`
+	MaxPerService int               `yaml:"maxPerService"`
+	Filter        _import.PodFilter `yaml:"filter"`
+	Import        Import            `yaml:",inline"`
+	// Generate imports synthetic workload instead of file, iteration seed is used if workload seed is 0
+	Generate *generate.Workload `yaml:"generate"`
+}
+
+// filter returns pods filter, maxPerService is a shorthand for filter.maxPerGroup
+func (p PodSource) filter() _import.PodFilter {
+	f := p.Filter
+	if f.MaxPerGroup == 0 {
+		f.MaxPerGroup = p.MaxPerService
+	}
+
+	return f
+}
+
+// Import describes import concurrency
+type Import struct {
+	Format string `yaml:"format"`
+	// Workers is a number of concurrent import requests, 0 or 1 - sequential import keeping scheduling order reproducible
+	Workers   int     `yaml:"workers"`
+	RateLimit float64 `yaml:"rateLimit"`
+}
+
+// options returns importer options, burst allows every worker to send a request at once
+func (i Import) options(logEnabled bool) _import.ImportOptions {
+	// format is validated by Validate
+	format, _ := source.ParseFormat(i.Format)
+
+	return _import.ImportOptions{
+		Format:     format,
+		Workers:    i.Workers,
+		RateLimit:  i.RateLimit,
+		Burst:      i.Workers,
+		LogEnabled: logEnabled,
+	}
+}
+
+// FieldError is a validation error pointing to the bad scenario field
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// ValidationErrors is a list of all validation errors found in scenario
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+
+	return "invalid scenario: " + strings.Join(msgs, "; ")
+}
+
+// Load reads scenario from YAML or JSON file and validates it
+func Load(filePath string) (*Scenario, error) {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := Parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return s, nil
+}
+
+// Parse decodes scenario from YAML or JSON (JSON is a subset of YAML) and validates it
+func Parse(contents []byte) (*Scenario, error) {
+	s := &Scenario{
+		Results: "./results",
+		Wait: Wait{
+			Timeout:      5 * time.Minute,
+			PollInterval: time.Second,
+			StablePolls:  2,
+		},
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(contents))
+	dec.KnownFields(true)
+	if err := dec.Decode(s); err != nil {
+		return nil, err
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Validate checks scenario fields and returns ValidationErrors if any of them is bad
+func (s *Scenario) Validate() error {
+	var errs ValidationErrors
+
+	if s.Iterations <= 0 {
+		errs = append(errs, &FieldError{"iterations", "must be greater than 0"})
+	}
+	if s.Results == "" {
+		errs = append(errs, &FieldError{"results", "results directory is required"})
+	}
+	if s.Wait.Timeout <= 0 {
+		errs = append(errs, &FieldError{"wait.timeout", "must be greater than 0"})
+	}
+	if s.Wait.PollInterval < 0 {
+		errs = append(errs, &FieldError{"wait.pollInterval", "must not be negative"})
+	}
+	if s.Wait.StablePolls < 0 {
+		errs = append(errs, &FieldError{"wait.stablePolls", "must not be negative"})
+	}
+	if len(s.Configs) == 0 {
+		errs = append(errs, &FieldError{"configs", "at least one scheduler config is required"})
+	}
+	configNames := map[string]int{}
+	for i, cfg := range s.Configs {
+		errs = appendFileError(errs, fmt.Sprintf("configs[%d]", i), cfg)
+
+		// results records are named and reported by config file name
+		name := result.ConfigName(cfg)
+		if j, ok := configNames[name]; ok {
+			errs = append(errs, &FieldError{fmt.Sprintf("configs[%d]", i), fmt.Sprintf("file name %s is already used by configs[%d]", name, j)})
+			continue
+		}
+		configNames[name] = i
+	}
+
+	if s.Nodes.Generate != nil {
+		if s.Nodes.File != "" {
+			errs = append(errs, &FieldError{"nodes.generate", "file and generate are mutually exclusive"})
+		}
+		if err := s.Nodes.Generate.Validate(); err != nil {
+			errs = append(errs, &FieldError{"nodes.generate", err.Error()})
+		}
+		if s.Nodes.Generate.TemplatesFile != "" {
+			errs = appendFileError(errs, "nodes.generate.templatesFile", s.Nodes.Generate.TemplatesFile)
+		}
+	} else {
+		errs = appendFileError(errs, "nodes.file", s.Nodes.File)
+	}
+	if s.Nodes.Limit < 0 {
+		errs = append(errs, &FieldError{"nodes.limit", "must not be negative"})
+	}
+	if s.Nodes.CoresEq < 0 {
+		errs = append(errs, &FieldError{"nodes.coresEq", "must not be negative"})
+	}
+	if err := s.Nodes.Filter.Validate(); err != nil {
+		errs = append(errs, &FieldError{"nodes.filter", err.Error()})
+	}
+
+	if s.Pods.Generate != nil {
+		if s.Pods.File != "" {
+			errs = append(errs, &FieldError{"pods.generate", "file and generate are mutually exclusive"})
+		}
+		if err := s.Pods.Generate.Validate(); err != nil {
+			errs = append(errs, &FieldError{"pods.generate", err.Error()})
+		}
+	} else {
+		errs = appendFileError(errs, "pods.file", s.Pods.File)
+	}
+	if s.Pods.Limit < 0 {
+		errs = append(errs, &FieldError{"pods.limit", "must not be negative"})
+	}
+	if s.Pods.MaxPerService < 0 {
+		errs = append(errs, &FieldError{"pods.maxPerService", "must not be negative"})
+	}
+	if err := s.Pods.Filter.Validate(); err != nil {
+		errs = append(errs, &FieldError{"pods.filter", err.Error()})
+	}
+
+	errs = appendImportErrors(errs, "nodes", s.Nodes.Import)
+	errs = appendImportErrors(errs, "pods", s.Pods.Import)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func appendImportErrors(errs ValidationErrors, field string, i Import) ValidationErrors {
+	if i.Workers < 0 {
+		errs = append(errs, &FieldError{field + ".workers", "must not be negative"})
+	}
+	if i.RateLimit < 0 {
+		errs = append(errs, &FieldError{field + ".rateLimit", "must not be negative"})
+	}
+	if _, err := source.ParseFormat(i.Format); err != nil {
+		errs = append(errs, &FieldError{field + ".format", err.Error()})
+	}
+
+	return errs
+}
+
+func appendFileError(errs ValidationErrors, field, filePath string) ValidationErrors {
+	if filePath == "" {
+		return append(errs, &FieldError{field, "file path is required"})
+	}
+
+	if _, err := os.Stat(filePath); err != nil {
+		return append(errs, &FieldError{field, err.Error()})
+	}
+
+	return errs
+}
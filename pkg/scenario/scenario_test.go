@@ -0,0 +1,138 @@
+package scenario
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/import"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(`
+name: test
+iterations: 2
+wait: {timeout: 1m, pollInterval: 500ms}
+configs: [scenario.go]
+nodes: {file: scenario.go, limit: 5, coresEq: 88}
+pods:
+  file: run.go
+  limit: 300
+  maxPerService: 3
+  filter: {excludeNamespaces: [kube-system], excludeOwnerKinds: [DaemonSet], cpu: {max: "4"}, groupBy: owner}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if s.Iterations != 2 || s.Wait.Timeout != time.Minute || s.Wait.PollInterval != 500*time.Millisecond || s.Wait.StablePolls != 2 || s.Nodes.CoresEq != 88 || s.Pods.MaxPerService != 3 {
+		t.Errorf("Parse() got unexpected scenario: %+v", s)
+	}
+
+	if f := s.Pods.filter(); f.GroupBy != "owner" || f.MaxPerGroup != 3 || f.CPU.Max != "4" || len(f.ExcludeOwnerKinds) != 1 {
+		t.Errorf("Parse() got unexpected pods filter: %+v", f)
+	}
+}
+
+func TestParseGenerate(t *testing.T) {
+	s, err := Parse([]byte(`
+iterations: 1
+configs: [scenario.go]
+nodes:
+  limit: 10
+  filter: {stratify: zone}
+  generate:
+    zones: [a, b]
+    templates:
+      - {count: 500, node: {metadata: {name: worker}, status: {allocatable: {cpu: "16"}}}}
+pods:
+  generate:
+    services:
+      - {name: web, count: 10, replicas: {value: "3"}, cpu: {type: uniform, min: 100m, max: "1"}, memory: {value: 1Gi}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	src, err := s.Nodes.Generate.Source()
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if b, err := src.Next(); err != nil || !strings.Contains(string(b), `"name":"worker-0"`) {
+		t.Errorf("Source() first node = %s, %v, want worker-0", b, err)
+	}
+
+	if s.Pods.Generate == nil || s.Pods.Generate.Services[0].Count != 10 {
+		t.Errorf("Parse() pods generate = %+v", s.Pods.Generate)
+	}
+
+	_, err = Parse([]byte(`
+iterations: 1
+configs: [scenario.go]
+nodes: {file: scenario.go, generate: {templates: [{from: worker}]}}
+pods: {file: scenario.go}
+`))
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Field != "nodes.generate" {
+		t.Errorf("Parse() error = %v, want file and templatesFile nodes.generate errors", err)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"iterations": 1,
+		"configs": ["scenario.go"],
+		"nodes": {"file": "scenario.go"},
+		"pods": {"file": "scenario.go"}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	_, err := Parse([]byte("iterations: 1\nnodes: {limits: 5}\n"))
+	if err == nil {
+		t.Fatal("Parse() expected error for unknown field")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := &Scenario{
+		Iterations: 0,
+		Results:    "./results",
+		Wait:       Wait{Timeout: time.Second},
+		Configs:    []string{"scenario.go", "missing.json", "../scenario/scenario.go"},
+		Nodes:      NodeSource{File: "scenario.go", Limit: -1},
+		Pods:       PodSource{Filter: _import.PodFilter{Selector: "app in (web"}},
+	}
+
+	var errs ValidationErrors
+	if !errors.As(s.Validate(), &errs) {
+		t.Fatalf("Validate() expected ValidationErrors")
+	}
+
+	want := []string{"iterations", "configs[1]", "configs[2]", "nodes.limit", "pods.file", "pods.filter"}
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, field := range want {
+		if errs[i].Field != field {
+			t.Errorf("Validate() error %d field = %s, want %s", i, errs[i].Field, field)
+		}
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	s := &Scenario{Name: "all", Results: "results"}
+	startedAt := time.Date(2022, 4, 1, 10, 20, 30, 0, time.UTC)
+
+	if got, want := s.runDir(startedAt.Add(5*time.Millisecond)), filepath.Join("results", "all-20220401-102030.005"); got != want {
+		t.Errorf("runDir() = %s, want %s", got, want)
+	}
+	if s.runDir(startedAt) == s.runDir(startedAt.Add(time.Millisecond)) {
+		t.Errorf("runDir() is equal for runs started within the same second")
+	}
+}
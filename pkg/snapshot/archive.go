@@ -0,0 +1,175 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	resourceNodes                  = "nodes"
+	resourcePods                   = "pods"
+	resourcePriorityClasses        = "priorityclasses"
+	resourcePersistentVolumeClaims = "persistentvolumeclaims"
+	resourcePersistentVolumes      = "persistentvolumes"
+	resourceStorageClasses         = "storageclasses"
+
+	fileManifest = "manifest.json"
+)
+
+// list is a kubectl style v1 List, so archive files can be imported as is after extraction
+type list struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Items      interface{} `json:"items"`
+}
+
+// WriteFile writes snapshot archive to file
+func WriteFile(path string, s *Snapshot) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = Write(f, s); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Write writes snapshot as a tar.gz archive: manifest.json and one <resource>.json List per resource
+func Write(w io.Writer, s *Snapshot) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := writeEntry(tw, fileManifest, s.Manifest, s.Manifest.CreatedAt); err != nil {
+		return err
+	}
+
+	for _, r := range s.resources() {
+		l := list{APIVersion: "v1", Kind: "List", Items: r.items}
+		if err := writeEntry(tw, r.name+".json", l, s.Manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// ReadFile reads snapshot archive from file
+func ReadFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Read reads snapshot from a tar.gz archive written by Write
+func Read(r io.Reader) (*Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	s := &Snapshot{}
+	items := map[string]interface{}{}
+	for _, r := range s.resources() {
+		items[r.name+".json"] = r.ptr
+	}
+
+	hasManifest := false
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if h.Name == fileManifest {
+			if err = json.NewDecoder(tr).Decode(&s.Manifest); err != nil {
+				return nil, fmt.Errorf("%s: %w", h.Name, err)
+			}
+			if s.Manifest.Version > Version {
+				return nil, fmt.Errorf("unsupported snapshot version %d, max supported is %d", s.Manifest.Version, Version)
+			}
+
+			hasManifest = true
+			continue
+		}
+
+		ptr, ok := items[h.Name]
+		if !ok {
+			continue
+		}
+
+		if err = json.NewDecoder(tr).Decode(&list{Items: ptr}); err != nil {
+			return nil, fmt.Errorf("%s: %w", h.Name, err)
+		}
+	}
+
+	if !hasManifest {
+		return nil, fmt.Errorf("%s not found, not a snapshot archive", fileManifest)
+	}
+
+	return s, nil
+}
+
+// resource is a snapshot resource with its items and pointer to items for decoding
+type resource struct {
+	name  string
+	items interface{}
+	ptr   interface{}
+}
+
+func (s *Snapshot) resources() []resource {
+	return []resource{
+		{resourceNodes, s.Nodes, &s.Nodes},
+		{resourcePods, s.Pods, &s.Pods},
+		{resourcePriorityClasses, s.PriorityClasses, &s.PriorityClasses},
+		{resourcePersistentVolumeClaims, s.PersistentVolumeClaims, &s.PersistentVolumeClaims},
+		{resourcePersistentVolumes, s.PersistentVolumes, &s.PersistentVolumes},
+		{resourceStorageClasses, s.StorageClasses, &s.StorageClasses},
+	}
+}
+
+func writeEntry(tw *tar.Writer, name string, v interface{}, modTime time.Time) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(b)
+	return err
+}
@@ -0,0 +1,211 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Version is the snapshot archive format version, archives with a newer version are rejected on read
+const Version = 1
+
+// DefaultPageSize is the number of objects fetched per list request, same as kubectl chunk size
+const DefaultPageSize = 500
+
+// Selector filters listed objects, same syntax as kubectl --selector and --field-selector
+type Selector struct {
+	Label string `json:"label,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// Options describes which objects are captured
+type Options struct {
+	Nodes Selector
+	Pods  Selector
+	// Namespace limits pods and persistent volume claims to a single namespace, empty - all namespaces
+	Namespace string
+	PageSize  int64
+}
+
+// Manifest describes snapshot archive contents
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Nodes     Selector       `json:"nodes"`
+	Pods      Selector       `json:"pods"`
+	Namespace string         `json:"namespace,omitempty"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// Snapshot is a point in time copy of cluster objects that affect scheduling
+type Snapshot struct {
+	Manifest               Manifest
+	Nodes                  []corev1.Node
+	Pods                   []corev1.Pod
+	PriorityClasses        []schedulingv1.PriorityClass
+	PersistentVolumeClaims []corev1.PersistentVolumeClaim
+	PersistentVolumes      []corev1.PersistentVolume
+	StorageClasses         []storagev1.StorageClass
+}
+
+// Take lists nodes, pods, priority classes, persistent volumes, claims and storage classes from cluster,
+// items get kind and apiVersion set like in kubectl get -o json output
+func Take(ctx context.Context, cs kubernetes.Interface, opts Options) (*Snapshot, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+
+	s := &Snapshot{
+		Manifest: Manifest{
+			Version:   Version,
+			CreatedAt: time.Now().UTC(),
+			Nodes:     opts.Nodes,
+			Pods:      opts.Pods,
+			Namespace: opts.Namespace,
+		},
+		Nodes:                  []corev1.Node{},
+		Pods:                   []corev1.Pod{},
+		PriorityClasses:        []schedulingv1.PriorityClass{},
+		PersistentVolumeClaims: []corev1.PersistentVolumeClaim{},
+		PersistentVolumes:      []corev1.PersistentVolume{},
+		StorageClasses:         []storagev1.StorageClass{},
+	}
+
+	err := paginate(opts.Nodes, opts.PageSize, func(lo metav1.ListOptions) (metav1.ListMeta, error) {
+		list, err := cs.CoreV1().Nodes().List(ctx, lo)
+		if err != nil {
+			return metav1.ListMeta{}, fmt.Errorf("list nodes: %w", err)
+		}
+
+		for i := range list.Items {
+			list.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Node"}
+		}
+		s.Nodes = append(s.Nodes, list.Items...)
+		return list.ListMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(opts.Pods, opts.PageSize, func(lo metav1.ListOptions) (metav1.ListMeta, error) {
+		list, err := cs.CoreV1().Pods(opts.Namespace).List(ctx, lo)
+		if err != nil {
+			return metav1.ListMeta{}, fmt.Errorf("list pods: %w", err)
+		}
+
+		for i := range list.Items {
+			list.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+		}
+		s.Pods = append(s.Pods, list.Items...)
+		return list.ListMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(Selector{}, opts.PageSize, func(lo metav1.ListOptions) (metav1.ListMeta, error) {
+		list, err := cs.SchedulingV1().PriorityClasses().List(ctx, lo)
+		if err != nil {
+			return metav1.ListMeta{}, fmt.Errorf("list priority classes: %w", err)
+		}
+
+		for i := range list.Items {
+			list.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: "scheduling.k8s.io/v1", Kind: "PriorityClass"}
+		}
+		s.PriorityClasses = append(s.PriorityClasses, list.Items...)
+		return list.ListMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(Selector{}, opts.PageSize, func(lo metav1.ListOptions) (metav1.ListMeta, error) {
+		list, err := cs.CoreV1().PersistentVolumeClaims(opts.Namespace).List(ctx, lo)
+		if err != nil {
+			return metav1.ListMeta{}, fmt.Errorf("list persistent volume claims: %w", err)
+		}
+
+		for i := range list.Items {
+			list.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"}
+		}
+		s.PersistentVolumeClaims = append(s.PersistentVolumeClaims, list.Items...)
+		return list.ListMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(Selector{}, opts.PageSize, func(lo metav1.ListOptions) (metav1.ListMeta, error) {
+		list, err := cs.CoreV1().PersistentVolumes().List(ctx, lo)
+		if err != nil {
+			return metav1.ListMeta{}, fmt.Errorf("list persistent volumes: %w", err)
+		}
+
+		for i := range list.Items {
+			list.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"}
+		}
+		s.PersistentVolumes = append(s.PersistentVolumes, list.Items...)
+		return list.ListMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = paginate(Selector{}, opts.PageSize, func(lo metav1.ListOptions) (metav1.ListMeta, error) {
+		list, err := cs.StorageV1().StorageClasses().List(ctx, lo)
+		if err != nil {
+			return metav1.ListMeta{}, fmt.Errorf("list storage classes: %w", err)
+		}
+
+		for i := range list.Items {
+			list.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: "storage.k8s.io/v1", Kind: "StorageClass"}
+		}
+		s.StorageClasses = append(s.StorageClasses, list.Items...)
+		return list.ListMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Manifest.Counts = s.counts()
+
+	return s, nil
+}
+
+// paginate calls list until server returns no continue token
+func paginate(sel Selector, pageSize int64, list func(lo metav1.ListOptions) (metav1.ListMeta, error)) error {
+	lo := metav1.ListOptions{
+		LabelSelector: sel.Label,
+		FieldSelector: sel.Field,
+		Limit:         pageSize,
+	}
+
+	for {
+		meta, err := list(lo)
+		if err != nil {
+			return err
+		}
+
+		if meta.Continue == "" {
+			return nil
+		}
+		lo.Continue = meta.Continue
+	}
+}
+
+func (s *Snapshot) counts() map[string]int {
+	return map[string]int{
+		resourcePods:                   len(s.Pods),
+		resourceNodes:                  len(s.Nodes),
+		resourcePriorityClasses:        len(s.PriorityClasses),
+		resourcePersistentVolumeClaims: len(s.PersistentVolumeClaims),
+		resourcePersistentVolumes:      len(s.PersistentVolumes),
+		resourceStorageClasses:         len(s.StorageClasses),
+	}
+}
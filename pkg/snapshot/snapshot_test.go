@@ -0,0 +1,179 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/d-ulyanov/kube-scheduler-benchmarks/pkg/source"
+)
+
+func testObjects() []runtime.Object {
+	return []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"role": "worker"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"role": "worker"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master-1", Labels: map[string]string{"role": "master"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"service": "web"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "default", Labels: map[string]string{"service": "db"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "prod", Labels: map[string]string{"service": "web"}}},
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high"}, Value: 1000},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "prod"}},
+		&corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "ssd"}, Provisioner: "csi.example.com"},
+	}
+}
+
+func TestTake(t *testing.T) {
+	cs := fake.NewSimpleClientset(testObjects()...)
+
+	s, err := Take(context.Background(), cs, Options{
+		Nodes:     Selector{Label: "role=worker"},
+		Pods:      Selector{Label: "service=web"},
+		Namespace: "default",
+	})
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	if got, want := nodeNames(s.Nodes), []string{"node-1", "node-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Take() nodes = %v, want %v", got, want)
+	}
+	if len(s.Pods) != 1 || s.Pods[0].Name != "web-1" {
+		t.Errorf("Take() pods = %v, want [web-1]", s.Pods)
+	}
+	if s.Pods[0].Kind != "Pod" || s.Pods[0].APIVersion != "v1" {
+		t.Errorf("Take() pod type meta = %v, want v1 Pod", s.Pods[0].TypeMeta)
+	}
+
+	wantCounts := map[string]int{
+		"nodes":                  2,
+		"pods":                   1,
+		"priorityclasses":        1,
+		"persistentvolumeclaims": 1,
+		"persistentvolumes":      1,
+		"storageclasses":         1,
+	}
+	if !reflect.DeepEqual(s.Manifest.Counts, wantCounts) {
+		t.Errorf("Take() counts = %v, want %v", s.Manifest.Counts, wantCounts)
+	}
+	if s.Manifest.Version != Version {
+		t.Errorf("Take() version = %d, want %d", s.Manifest.Version, Version)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	cs := fake.NewSimpleClientset(testObjects()...)
+
+	s, err := Take(context.Background(), cs, Options{})
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	if err = WriteFile(path, s); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if !got.Manifest.CreatedAt.Equal(s.Manifest.CreatedAt) {
+		t.Errorf("ReadFile() createdAt = %v, want %v", got.Manifest.CreatedAt, s.Manifest.CreatedAt)
+	}
+	got.Manifest.CreatedAt = s.Manifest.CreatedAt
+
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("ReadFile() = %+v, want %+v", got, s)
+	}
+}
+
+func TestArchiveFilesAreImportable(t *testing.T) {
+	cs := fake.NewSimpleClientset(testObjects()...)
+
+	s, err := Take(context.Background(), cs, Options{})
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = Write(buf, s); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	gr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			t.Fatalf("pods.json not found in archive: %v", err)
+		}
+		if h.Name == "pods.json" {
+			break
+		}
+	}
+
+	src, err := source.New(bufio.NewReader(tr), source.FormatAuto)
+	if err != nil {
+		t.Fatalf("source.New() error = %v", err)
+	}
+
+	count := 0
+	for {
+		_, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		count++
+	}
+
+	if count != len(s.Pods) {
+		t.Errorf("read %d pods, want %d", count, len(s.Pods))
+	}
+}
+
+func TestReadRejectsNewerVersion(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	err := Write(buf, &Snapshot{Manifest: Manifest{Version: Version + 1}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if _, err = Read(buf); err == nil {
+		t.Error("Read() error = nil, want unsupported version error")
+	}
+}
+
+func nodeNames(nodes []corev1.Node) []string {
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
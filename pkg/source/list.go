@@ -0,0 +1,93 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ListReader streams objects of `items` array of a kubernetes List, so only one object is kept in memory at a time
+type ListReader struct {
+	dec     *json.Decoder
+	started bool
+	done    bool
+	index   int
+}
+
+// NewListReader returns List reader
+func NewListReader(r io.Reader) *ListReader {
+	return &ListReader{dec: json.NewDecoder(r)}
+}
+
+// Close does nothing, underlying reader is closed by its owner
+func (r *ListReader) Close() error {
+	return nil
+}
+
+// Next returns next object of `items` array
+func (r *ListReader) Next() ([]byte, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	if !r.started {
+		r.started = true
+		if err := r.seekItems(); err != nil {
+			r.done = true
+			return nil, err
+		}
+	}
+
+	if !r.dec.More() {
+		r.done = true
+		return nil, io.EOF
+	}
+
+	var item json.RawMessage
+	if err := r.dec.Decode(&item); err != nil {
+		r.done = true
+		return nil, fmt.Errorf("items[%d]: %w", r.index, err)
+	}
+	r.index++
+
+	return item, nil
+}
+
+// seekItems moves decoder to the first element of top level `items` array skipping other fields
+func (r *ListReader) seekItems() error {
+	if err := r.expectDelim('{'); err != nil {
+		return err
+	}
+
+	for r.dec.More() {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if key, _ := tok.(string); key == "items" {
+			return r.expectDelim('[')
+		}
+
+		// skip field value
+		var skip json.RawMessage
+		if err = r.dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("items field not found")
+}
+
+func (r *ListReader) expectDelim(delim json.Delim) error {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %s, got %v", delim, tok)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package source
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestListReader(t *testing.T) {
+	r := NewListReader(strings.NewReader(`{
+		"apiVersion": "v1",
+		"metadata": {"resourceVersion": "", "nested": {"items": [1]}},
+		"items": [{"metadata": {"name": "a"}}, {"metadata": {"name": "b"}}],
+		"kind": "List"
+	}`))
+
+	var got []string
+	for {
+		item, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, string(item))
+	}
+
+	want := []string{`{"metadata": {"name": "a"}}`, `{"metadata": {"name": "b"}}`}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Next() got = %v, want %v", got, want)
+	}
+}
+
+func TestListReaderErrors(t *testing.T) {
+	for _, in := range []string{`[]`, `{"kind": "List"}`, `{"items": [{"a": 1}, {"b": }]}`} {
+		r := NewListReader(strings.NewReader(in))
+
+		var err error
+		for err == nil {
+			_, err = r.Next()
+		}
+
+		if err == io.EOF {
+			t.Errorf("Next() for %s expected error", in)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package source
+
+import "io"
+
+// MemorySource returns objects kept in memory, e.g. generated or read ahead for sampling
+type MemorySource struct {
+	items [][]byte
+	pos   int
+}
+
+// NewMemorySource returns source of given objects JSON
+func NewMemorySource(items [][]byte) *MemorySource {
+	return &MemorySource{items: items}
+}
+
+// Next returns next object JSON and io.EOF when there are no more objects
+func (s *MemorySource) Next() ([]byte, error) {
+	if s.pos >= len(s.items) {
+		return nil, io.EOF
+	}
+
+	item := s.items[s.pos]
+	s.pos++
+
+	return item, nil
+}
+
+// Close does nothing, objects are kept in memory
+func (s *MemorySource) Close() error {
+	return nil
+}
@@ -0,0 +1,254 @@
+// Package source reads kubernetes objects from JSON, NDJSON and YAML files and directories of manifests
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Source returns kubernetes objects JSON one at a time
+type Source interface {
+	// Next returns next object JSON and io.EOF when there are no more objects
+	Next() ([]byte, error)
+	// Close releases underlying files
+	Close() error
+}
+
+// Format is an input format
+type Format string
+
+const (
+	// FormatAuto detects format by file extension and content
+	FormatAuto Format = ""
+	// FormatJSON is a kubernetes List, a single object or concatenated objects
+	FormatJSON Format = "json"
+	// FormatNDJSON is a newline delimited stream of objects or Lists
+	FormatNDJSON Format = "ndjson"
+	// FormatYAML is a single or multi-document YAML of objects or Lists
+	FormatYAML Format = "yaml"
+)
+
+// sniffSize is a size of file prefix used for format detection
+const sniffSize = 64 * 1024
+
+// ParseFormat validates format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatAuto, FormatJSON, FormatNDJSON, FormatYAML:
+		return f, nil
+	case "auto":
+		return FormatAuto, nil
+	case "yml":
+		return FormatYAML, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unknown input format %q, expected one of: json, ndjson, yaml", name)
+	}
+}
+
+// Open opens file or directory of manifests, directory files are read in name order
+func Open(path string, format Format) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return openDir(path, format)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := New(bufio.NewReaderSize(f, sniffSize), detectByExtension(path, format))
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &fileSource{Source: src, path: path, f: f}, nil
+}
+
+// New returns source reading objects of given format, format is detected by content if it's FormatAuto
+func New(r *bufio.Reader, format Format) (Source, error) {
+	prefix, err := r.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	if format == FormatAuto {
+		format = detectByContent(prefix)
+	}
+
+	switch format {
+	case FormatJSON:
+		if isList(prefix) {
+			return NewListReader(r), nil
+		}
+		return NewJSONStreamReader(r), nil
+	case FormatNDJSON:
+		return NewJSONStreamReader(r), nil
+	case FormatYAML:
+		return NewYAMLReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
+
+func detectByExtension(path string, format Format) Format {
+	if format != FormatAuto {
+		return format
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+
+	return FormatAuto
+}
+
+func detectByContent(prefix []byte) Format {
+	trimmed := bytes.TrimLeft(prefix, " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+
+	return FormatYAML
+}
+
+// isList checks if JSON document prefix is a kubernetes List by its top level `items` or `kind` fields
+func isList(prefix []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(prefix))
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+
+		switch tok {
+		case "items":
+			return true
+		case "kind":
+			kind, err := dec.Token()
+			if err != nil {
+				return false
+			}
+			if s, _ := kind.(string); strings.HasSuffix(s, "List") {
+				return true
+			}
+		default:
+			var skip json.RawMessage
+			if err = dec.Decode(&skip); err != nil {
+				return false
+			}
+		}
+	}
+
+	return false
+}
+
+// fileSource closes file together with source
+type fileSource struct {
+	Source
+	path string
+	f    *os.File
+}
+
+func (s *fileSource) Next() ([]byte, error) {
+	b, err := s.Source.Next()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	return b, err
+}
+
+func (s *fileSource) Close() error {
+	_ = s.Source.Close()
+
+	return s.f.Close()
+}
+
+// manifestExtensions are files read from directories
+var manifestExtensions = map[string]bool{".json": true, ".ndjson": true, ".jsonl": true, ".yaml": true, ".yml": true}
+
+// dirSource reads all manifests of directory one file at a time
+type dirSource struct {
+	files   []string
+	format  Format
+	current Source
+}
+
+func openDir(dir string, format Format) (Source, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && manifestExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	return &dirSource{files: files, format: format}, nil
+}
+
+func (s *dirSource) Next() ([]byte, error) {
+	for {
+		if s.current == nil {
+			if len(s.files) == 0 {
+				return nil, io.EOF
+			}
+
+			src, err := Open(s.files[0], s.format)
+			if err != nil {
+				return nil, err
+			}
+
+			s.current = src
+			s.files = s.files[1:]
+		}
+
+		b, err := s.current.Next()
+		if err != io.EOF {
+			return b, err
+		}
+
+		if err = s.current.Close(); err != nil {
+			return nil, err
+		}
+		s.current = nil
+	}
+}
+
+func (s *dirSource) Close() error {
+	if s.current != nil {
+		return s.current.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package source
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readNames(t *testing.T, src Source) []string {
+	t.Helper()
+
+	names := []string{}
+	for {
+		b, err := src.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+
+		obj := struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}{}
+		if err = json.Unmarshal(b, &obj); err != nil {
+			t.Fatalf("Next() returned invalid json: %v: %s", err, b)
+		}
+
+		names = append(names, obj.Metadata.Name)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		in     string
+		want   []string
+	}{
+		{
+			name: "json list",
+			in:   `{"apiVersion": "v1", "items": [{"metadata": {"name": "a"}}, {"metadata": {"name": "b"}}], "kind": "List"}`,
+			want: []string{"a", "b"},
+		},
+		{
+			name: "json single object",
+			in:   `{"kind": "Pod", "metadata": {"name": "a"}}`,
+			want: []string{"a"},
+		},
+		{
+			name: "json array",
+			in:   `[{"metadata": {"name": "a"}}, {"metadata": {"name": "b"}}]`,
+			want: []string{"a", "b"},
+		},
+		{
+			name:   "ndjson",
+			format: FormatNDJSON,
+			in:     "{\"metadata\": {\"name\": \"a\"}}\n{\"kind\": \"PodList\", \"items\": [{\"metadata\": {\"name\": \"b\"}}]}\n",
+			want:   []string{"a", "b"},
+		},
+		{
+			name: "multi-document yaml",
+			in: `---
+kind: Pod
+metadata:
+  name: a
+  creationTimestamp: 2022-04-01T10:00:00Z
+---
+---
+apiVersion: v1
+kind: List
+items:
+- kind: Pod
+  metadata:
+    name: b
+- kind: Pod
+  metadata:
+    name: c
+`,
+			want: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := New(bufio.NewReader(strings.NewReader(tt.in)), tt.format)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if got := readNames(t, src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() read = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"1-pods.json":  `{"items": [{"metadata": {"name": "a"}}]}`,
+		"2-pod.yaml":   "metadata:\n  name: b\n",
+		"3-pods.jsonl": "{\"metadata\": {\"name\": \"c\"}}\n",
+		"README.md":    "not a manifest",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Open(dir, FormatAuto)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer src.Close()
+
+	if got, want := readNames(t, src), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Open() read = %v, want %v", got, want)
+	}
+}
+
+func TestDetectByContent(t *testing.T) {
+	if f := detectByContent([]byte("  \n{}")); f != FormatJSON {
+		t.Errorf("detectByContent() = %s, want json", f)
+	}
+	if f := detectByContent([]byte("kind: Pod")); f != FormatYAML {
+		t.Errorf("detectByContent() = %s, want yaml", f)
+	}
+}
@@ -0,0 +1,168 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONStreamReader reads concatenated or newline delimited JSON values,
+// List values and arrays are expanded to their items
+type JSONStreamReader struct {
+	dec     *json.Decoder
+	pending [][]byte
+	index   int
+}
+
+// NewJSONStreamReader returns JSON stream reader
+func NewJSONStreamReader(r io.Reader) *JSONStreamReader {
+	return &JSONStreamReader{dec: json.NewDecoder(r)}
+}
+
+// Next returns next object
+func (r *JSONStreamReader) Next() ([]byte, error) {
+	for len(r.pending) == 0 {
+		var v json.RawMessage
+		if err := r.dec.Decode(&v); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("value #%d: %w", r.index, err)
+		}
+		r.index++
+
+		items, err := expandJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("value #%d: %w", r.index-1, err)
+		}
+		r.pending = items
+	}
+
+	item := r.pending[0]
+	r.pending = r.pending[1:]
+
+	return item, nil
+}
+
+// Close does nothing, underlying reader is closed by its owner
+func (r *JSONStreamReader) Close() error {
+	return nil
+}
+
+// expandJSON returns items of List or array value and the value itself otherwise
+func expandJSON(v json.RawMessage) ([][]byte, error) {
+	trimmed := bytes.TrimLeft(v, " \t\r\n")
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var items []json.RawMessage
+
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(v, &items); err != nil {
+			return nil, err
+		}
+	case '{':
+		list := struct {
+			Kind  string            `json:"kind"`
+			Items []json.RawMessage `json:"items"`
+		}{}
+		if err := json.Unmarshal(v, &list); err != nil {
+			return nil, err
+		}
+		if list.Items == nil && !strings.HasSuffix(list.Kind, "List") {
+			return [][]byte{v}, nil
+		}
+		items = list.Items
+	default:
+		return nil, fmt.Errorf("expected object, List or array")
+	}
+
+	out := make([][]byte, 0, len(items))
+	for _, item := range items {
+		out = append(out, item)
+	}
+
+	return out, nil
+}
+
+// YAMLReader reads single or multi-document YAML, List documents are expanded to their items
+type YAMLReader struct {
+	dec     *yaml.Decoder
+	pending [][]byte
+	index   int
+}
+
+// NewYAMLReader returns YAML reader
+func NewYAMLReader(r io.Reader) *YAMLReader {
+	return &YAMLReader{dec: yaml.NewDecoder(r)}
+}
+
+// Next returns next object converted to JSON
+func (r *YAMLReader) Next() ([]byte, error) {
+	for len(r.pending) == 0 {
+		var doc interface{}
+		if err := r.dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("document #%d: %w", r.index, err)
+		}
+		r.index++
+
+		// empty documents between separators
+		if doc == nil {
+			continue
+		}
+
+		b, err := json.Marshal(jsonCompatible(doc))
+		if err != nil {
+			return nil, fmt.Errorf("document #%d: %w", r.index-1, err)
+		}
+
+		items, err := expandJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("document #%d: %w", r.index-1, err)
+		}
+		r.pending = items
+	}
+
+	item := r.pending[0]
+	r.pending = r.pending[1:]
+
+	return item, nil
+}
+
+// Close does nothing, underlying reader is closed by its owner
+func (r *YAMLReader) Close() error {
+	return nil
+}
+
+// jsonCompatible converts YAML maps with non-string keys to JSON objects
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = jsonCompatible(val)
+		}
+		return v
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return out
+	case []interface{}:
+		for i, val := range v {
+			v[i] = jsonCompatible(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,49 @@
+package source
+
+import (
+	"bufio"
+	"io"
+)
+
+// ListWriter writes items as a kubectl style v1 List without keeping them in memory
+type ListWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+// NewListWriter returns a List writer, Close must be called to finish the List
+func NewListWriter(w io.Writer) (*ListWriter, error) {
+	lw := &ListWriter{w: bufio.NewWriter(w)}
+	if _, err := lw.w.WriteString(`{"apiVersion":"v1","kind":"List","items":[`); err != nil {
+		return nil, err
+	}
+
+	return lw, nil
+}
+
+// Write appends a JSON encoded item to the List
+func (lw *ListWriter) Write(item []byte) error {
+	if lw.count > 0 {
+		if err := lw.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	lw.count++
+
+	_, err := lw.w.Write(item)
+	return err
+}
+
+// Count returns number of written items
+func (lw *ListWriter) Count() int {
+	return lw.count
+}
+
+// Close finishes the List and flushes it, underlying writer is not closed
+func (lw *ListWriter) Close() error {
+	if _, err := lw.w.WriteString("]}"); err != nil {
+		return err
+	}
+
+	return lw.w.Flush()
+}
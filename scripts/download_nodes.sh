@@ -1,5 +0,0 @@
-#!/bin/bash
-
-kubectl get no -o wide -l node-role.kubernetes.io/includedRole=,\
-node-role.kubernetes.io/excluededRole!= \
--o json > ./testdata/nodes.json
\ No newline at end of file